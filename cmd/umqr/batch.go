@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
+	"github.com/frankmwase/malawi-pay-standard/pkg/qrcode"
+	"github.com/frankmwase/malawi-pay-standard/pkg/umqr"
+)
+
+// batchColumns is the CSV layout. A first row starting with "name" is
+// treated as a header.
+var batchColumns = []string{"name", "city", "alias", "provider", "mcc", "amount"}
+
+// sticker is one manifest entry.
+type sticker struct {
+	Row              int          `json:"row"`
+	MerchantName     string       `json:"merchant_name"`
+	City             string       `json:"city"`
+	Alias            string       `json:"alias"`
+	Provider         string       `json:"provider"`
+	MCC              string       `json:"mcc"`
+	Amount           mwjson.Money `json:"amount,omitzero"`
+	Payload          string       `json:"payload"`
+	CRC              string       `json:"crc"`
+	VerificationCode string       `json:"verification_code"`
+	Files            []string     `json:"files"`
+}
+
+// manifest is written next to the sheets for audit.
+type manifest struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Source      string    `json:"source"`
+	Stickers    []sticker `json:"stickers"`
+}
+
+// sheetWriters renders a sticker in each output format, keyed by extension.
+var sheetWriters = map[string]func(path string, s *sticker) error{
+	"svg": writeSheet,
+	"pdf": writePDF,
+}
+
+// runBatch generates a sticker sheet per CSV row. Every row is validated
+// first; if any row fails nothing is written.
+func runBatch(args []string) int {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	in := fs.String("in", "", "Merchant CSV: "+strings.Join(batchColumns, ",")+" (amount optional)")
+	out := fs.String("out", "stickers", "Output directory")
+	format := fs.String("format", "svg,pdf", "Comma-separated sheet formats: svg, pdf")
+	fs.Parse(args)
+
+	if *in == "" {
+		fmt.Fprintln(os.Stderr, "umqr batch: -in is required")
+		fs.Usage()
+		return 2
+	}
+	formats, err := parseFormats(*format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "umqr batch: %v\n", err)
+		return 2
+	}
+
+	f, err := os.Open(*in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "umqr batch: %v\n", err)
+		return 2
+	}
+	defer f.Close()
+
+	stickers, errs := readMerchants(f, formats)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *in, err)
+		}
+		fmt.Fprintf(os.Stderr, "umqr batch: %d invalid row(s), nothing written\n", len(errs))
+		return 1
+	}
+	if len(stickers) == 0 {
+		fmt.Fprintln(os.Stderr, "umqr batch: no merchants in input")
+		return 1
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "umqr batch: %v\n", err)
+		return 2
+	}
+	for i := range stickers {
+		for _, name := range stickers[i].Files {
+			write := sheetWriters[strings.TrimPrefix(filepath.Ext(name), ".")]
+			if err := write(filepath.Join(*out, name), &stickers[i]); err != nil {
+				fmt.Fprintf(os.Stderr, "umqr batch: row %d: %v\n", stickers[i].Row, err)
+				return 1
+			}
+		}
+	}
+
+	if err := writeManifest(filepath.Join(*out, "manifest.json"), filepath.Base(*in), stickers); err != nil {
+		fmt.Fprintf(os.Stderr, "umqr batch: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Wrote %d sticker sheet(s) and manifest.json to %s\n", len(stickers), *out)
+	return 0
+}
+
+// parseFormats checks a -format value against sheetWriters.
+func parseFormats(s string) ([]string, error) {
+	var formats []string
+	for _, f := range strings.Split(s, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if _, ok := sheetWriters[f]; !ok {
+			return nil, fmt.Errorf("unknown sheet format %q", f)
+		}
+		formats = append(formats, f)
+	}
+	return formats, nil
+}
+
+// writeManifest writes the audit manifest for stickers.
+func writeManifest(path, source string, stickers []sticker) error {
+	m := manifest{GeneratedAt: time.Now().UTC(), Source: source, Stickers: stickers}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// readMerchants parses and validates every row, returning one error per
+// bad row. Each sticker lists one sheet file per format.
+func readMerchants(r io.Reader, formats []string) ([]sticker, []error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var out []sticker
+	var errs []error
+	for i, rec := range records {
+		row := i + 1
+		if i == 0 && len(rec) > 0 && strings.EqualFold(strings.TrimSpace(rec[0]), batchColumns[0]) {
+			continue
+		}
+		s, err := buildSticker(row, rec, formats)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("row %d: %w", row, err))
+			continue
+		}
+		out = append(out, s)
+	}
+	return out, errs
+}
+
+func buildSticker(row int, rec []string, formats []string) (sticker, error) {
+	if len(rec) < 5 || len(rec) > 6 {
+		return sticker{}, fmt.Errorf("expected 5 or 6 columns (%s), got %d", strings.Join(batchColumns, ","), len(rec))
+	}
+	for i := range rec {
+		rec[i] = strings.TrimSpace(rec[i])
+	}
+
+	s := sticker{
+		Row:          row,
+		MerchantName: rec[0],
+		City:         rec[1],
+		Alias:        rec[2],
+		Provider:     rec[3],
+		MCC:          rec[4],
+	}
+	if len(rec) == 6 && rec[5] != "" {
+		amount, err := mwjson.ParseMoney(rec[5])
+		if err != nil || !amount.IsPositive() {
+			return sticker{}, fmt.Errorf("invalid amount %q", rec[5])
+		}
+		s.Amount = amount
+	}
+
+	payload, err := umqr.GenerateMerchantQR(s.MerchantName, s.City, s.Alias, s.Provider, s.MCC, s.Amount, "")
+	if err != nil {
+		return sticker{}, err
+	}
+	if v := umqr.Lint(payload); !umqr.Conforms(v) {
+		return sticker{}, fmt.Errorf("generated payload fails lint: %s", v[0])
+	}
+
+	s.Payload = payload
+	s.CRC = payload[len(payload)-4:]
+	s.VerificationCode = umqr.VerificationCode(payload)
+	for _, f := range formats {
+		s.Files = append(s.Files, fmt.Sprintf("%03d_%s.%s", row, slug(s.Alias), f))
+	}
+	return s, nil
+}
+
+// Sheet layout in SVG user units (1 unit = 1 mm), A6 portrait.
+const (
+	sheetWidth  = 105
+	sheetHeight = 148
+	qrSide      = 80
+)
+
+// writeSheet renders a print-ready A6 SVG sticker: merchant name, QR,
+// alias, provider and the verification code.
+func writeSheet(path string, s *sticker) error {
+	sym, err := qrcode.New(s.Payload, qrcode.Options{Level: qrcode.Medium})
+	if err != nil {
+		return err
+	}
+
+	units := sym.Size() + 2*qrcode.DefaultQuietZone
+	scale := float64(qrSide) / float64(units)
+	qrX := float64(sheetWidth-qrSide) / 2
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%dmm" height="%dmm" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif" text-anchor="middle">`+"\n",
+		sheetWidth, sheetHeight, sheetWidth, sheetHeight)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#FFFFFF" stroke="#000000" stroke-width="0.3"/>`+"\n", sheetWidth, sheetHeight)
+	fmt.Fprintf(&b, `<text x="%.1f" y="16" font-size="7" font-weight="bold">%s</text>`+"\n", sheetWidth/2.0, escapeXML(s.MerchantName))
+	fmt.Fprintf(&b, `<text x="%.1f" y="23" font-size="4">%s</text>`+"\n", sheetWidth/2.0, escapeXML(s.City))
+
+	fmt.Fprintf(&b, `<g transform="translate(%.2f 28) scale(%.4f)" shape-rendering="crispEdges">`, qrX, scale)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#FFFFFF"/><path fill="#000000" d="`, units, units)
+	for y := 0; y < sym.Size(); y++ {
+		for x := 0; x < sym.Size(); x++ {
+			if sym.Module(x, y) {
+				fmt.Fprintf(&b, "M%d %dh1v1h-1z", x+qrcode.DefaultQuietZone, y+qrcode.DefaultQuietZone)
+			}
+		}
+	}
+	b.WriteString("\"/></g>\n")
+
+	fmt.Fprintf(&b, `<text x="%.1f" y="120" font-size="8" font-weight="bold">%s</text>`+"\n", sheetWidth/2.0, escapeXML(s.Alias))
+	fmt.Fprintf(&b, `<text x="%.1f" y="128" font-size="4">%s</text>`+"\n", sheetWidth/2.0, escapeXML(strings.ReplaceAll(s.Provider, "_", " ")))
+	if s.Amount.IsPositive() {
+		fmt.Fprintf(&b, `<text x="%.1f" y="134" font-size="4">MWK %s</text>`+"\n", sheetWidth/2.0, s.Amount)
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="142" font-size="3.5" font-family="Courier, monospace">Code: %s</text>`+"\n", sheetWidth/2.0, s.VerificationCode)
+	b.WriteString("</svg>\n")
+
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// slug turns an alias into a safe file name component.
+func slug(alias string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimPrefix(alias, "@")) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
+	"github.com/frankmwase/malawi-pay-standard/pkg/umqr"
+)
+
+func TestReadMerchants(t *testing.T) {
+	tests := []struct {
+		name string
+		row  string
+		err  string // Substring of the row error; empty for a valid row
+	}{
+		{"valid", "MUBAS Cafe,Blantyre,@mubas_cafe,AIRTEL_MONEY,9801", ""},
+		{"valid with amount", "Kabaza Stop,Zomba,@kabaza1,TNM_MPAMBA,9802,500.50", ""},
+		{"too few columns", "MUBAS Cafe,Blantyre,@mubas_cafe,AIRTEL_MONEY", "expected 5 or 6 columns"},
+		{"too many columns", "MUBAS Cafe,Blantyre,@mubas_cafe,AIRTEL_MONEY,9801,100,extra", "expected 5 or 6 columns"},
+		{"unknown MCC", "MUBAS Cafe,Blantyre,@mubas_cafe,AIRTEL_MONEY,0000", "merchant category code"},
+		{"bad amount", "MUBAS Cafe,Blantyre,@mubas_cafe,AIRTEL_MONEY,9801,12.345", "invalid amount"},
+		{"zero amount", "MUBAS Cafe,Blantyre,@mubas_cafe,AIRTEL_MONEY,9801,0", "invalid amount"},
+		{"name too long", strings.Repeat("A", 26) + ",Blantyre,@mubas_cafe,AIRTEL_MONEY,9801", "59"},
+		{"missing alias", "MUBAS Cafe,Blantyre,,AIRTEL_MONEY,9801", "alias"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stickers, errs := readMerchants(strings.NewReader(tt.row), []string{"svg", "pdf"})
+			if tt.err == "" {
+				if len(errs) != 0 || len(stickers) != 1 {
+					t.Fatalf("readMerchants = %v, %v; want one sticker", stickers, errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.err) || len(stickers) != 0 {
+				t.Errorf("readMerchants errors = %v; want one containing %q", errs, tt.err)
+			}
+		})
+	}
+}
+
+func TestBatchManifest(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "merchants.csv")
+	csv := "name,city,alias,provider,mcc,amount\n" +
+		"MUBAS Cafe,Blantyre,@mubas_cafe,AIRTEL_MONEY,9801,\n" +
+		"Kabaza Stop,Zomba,@kabaza1,TNM_MPAMBA,9802,500\n"
+	if err := os.WriteFile(in, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	if code := runBatch([]string{"-in", in, "-out", out}); code != 0 {
+		t.Fatalf("runBatch exit code = %d", code)
+	}
+
+	data, err := os.ReadFile(filepath.Join(out, "manifest.json"))
+	if err != nil {
+		t.Fatalf("reading manifest: %v", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("parsing manifest: %v", err)
+	}
+	if m.Source != "merchants.csv" || len(m.Stickers) != 2 {
+		t.Fatalf("manifest = %+v", m)
+	}
+
+	for _, s := range m.Stickers {
+		q, err := umqr.Decode(s.Payload)
+		if err != nil {
+			t.Errorf("row %d payload does not decode: %v", s.Row, err)
+			continue
+		}
+		if q.MerchantAccount.Alias != s.Alias || q.MCC != s.MCC {
+			t.Errorf("row %d payload = %+v, manifest = %+v", s.Row, q, s)
+		}
+		if s.CRC != s.Payload[len(s.Payload)-4:] || s.VerificationCode != umqr.VerificationCode(s.Payload) {
+			t.Errorf("row %d CRC %s or code %s does not match payload", s.Row, s.CRC, s.VerificationCode)
+		}
+		if len(s.Files) != 2 {
+			t.Errorf("row %d files = %v, want svg and pdf", s.Row, s.Files)
+		}
+		for _, f := range s.Files {
+			sheet, err := os.ReadFile(filepath.Join(out, f))
+			if err != nil {
+				t.Errorf("row %d: %v", s.Row, err)
+				continue
+			}
+			if strings.HasSuffix(f, ".pdf") && !strings.HasPrefix(string(sheet), "%PDF-") {
+				t.Errorf("%s is not a PDF", f)
+			}
+			if !strings.Contains(string(sheet), s.VerificationCode) {
+				t.Errorf("%s does not show verification code %s", f, s.VerificationCode)
+			}
+		}
+	}
+	if m.Stickers[1].Amount != mwjson.Kwacha(500) {
+		t.Errorf("row 3 amount = %s, want 500.00", m.Stickers[1].Amount)
+	}
+
+	// A bad row stops the whole batch before anything is written.
+	bad := filepath.Join(dir, "bad.csv")
+	os.WriteFile(bad, []byte(csv+"Broken,Zomba,@broken,TNM_MPAMBA,12\n"), 0o644)
+	empty := filepath.Join(dir, "empty")
+	if code := runBatch([]string{"-in", bad, "-out", empty}); code != 1 {
+		t.Errorf("runBatch with bad row exit code = %d, want 1", code)
+	}
+	if _, err := os.Stat(empty); !os.IsNotExist(err) {
+		t.Errorf("output directory created despite bad row: %v", err)
+	}
+}
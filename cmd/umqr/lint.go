@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/umqr"
+)
+
+// lintResult is the report for one payload.
+type lintResult struct {
+	Source     string           `json:"source"` // "arg 1" or "file:line"
+	Payload    string           `json:"payload"`
+	Violations []umqr.Violation `json:"violations"`
+}
+
+// runLint lints payloads given as arguments or read from a file, one per
+// line. Blank lines and lines starting with "#" are skipped. It returns 1
+// when any payload has errors; warnings are reported but do not fail.
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	file := fs.String("f", "", `File with one payload per line ("-" for stdin)`)
+	fs.Parse(args)
+
+	var results []lintResult
+	for i, p := range fs.Args() {
+		results = append(results, lintResult{Source: fmt.Sprintf("arg %d", i+1), Payload: p})
+	}
+	if *file != "" {
+		fromFile, err := readPayloads(*file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "umqr lint: %v\n", err)
+			return 2
+		}
+		results = append(results, fromFile...)
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(os.Stderr, "umqr lint: no payloads given")
+		fs.Usage()
+		return 2
+	}
+
+	failed := 0
+	for i := range results {
+		results[i].Violations = umqr.Lint(results[i].Payload)
+		if results[i].Violations == nil {
+			results[i].Violations = []umqr.Violation{}
+		}
+		if !umqr.Conforms(results[i].Violations) {
+			failed++
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(results)
+	} else {
+		printLint(os.Stdout, results)
+		fmt.Printf("\n%d of %d payloads conform\n", len(results)-failed, len(results))
+	}
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printLint(w io.Writer, results []lintResult) {
+	for _, r := range results {
+		if len(r.Violations) == 0 {
+			fmt.Fprintf(w, "%s: OK\n", r.Source)
+			continue
+		}
+		fmt.Fprintf(w, "%s: %d violation(s)\n", r.Source, len(r.Violations))
+		for _, v := range r.Violations {
+			fmt.Fprintf(w, "  %s\n", v)
+		}
+	}
+}
+
+func readPayloads(path string) ([]lintResult, error) {
+	var f io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		f = file
+	}
+
+	var out []lintResult
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, lintResult{Source: fmt.Sprintf("%s:%d", path, n), Payload: line})
+	}
+	return out, sc.Err()
+}
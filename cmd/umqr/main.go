@@ -0,0 +1,36 @@
+// Command umqr works with Universal Malawi QR payloads.
+//
+//	umqr lint [-json] [-f file] [payload ...]
+//	umqr batch -in merchants.csv [-out dir]
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: umqr <command> [flags]
+
+Commands:
+  lint   Check payloads against the EMVCo and Malawi UMQR rules
+  batch  Generate sticker sheets and a manifest from a merchant CSV
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "lint":
+		os.Exit(runLint(os.Args[2:]))
+	case "batch":
+		os.Exit(runBatch(os.Args[2:]))
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "umqr: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/qrcode"
+)
+
+// ptPerMM converts the sheet's millimetre layout to PDF points.
+const ptPerMM = 72 / 25.4
+
+// PDF base-14 fonts used on the sheet. They need no embedding.
+const (
+	fontRegular = "F1" // Helvetica
+	fontBold    = "F2" // Helvetica-Bold
+	fontMono    = "F3" // Courier
+)
+
+// Glyph widths in 1/1000 em for printable ASCII (32-126), from the Adobe
+// font metrics. Courier is 600 throughout.
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// writePDF renders the same A6 sticker as writeSheet as a one-page PDF,
+// for print shops that do not take SVG.
+func writePDF(path string, s *sticker) error {
+	sym, err := qrcode.New(s.Payload, qrcode.Options{Level: qrcode.Medium})
+	if err != nil {
+		return err
+	}
+	units := sym.Size() + 2*qrcode.DefaultQuietZone
+	scale := float64(qrSide) / float64(units)
+	qrX := float64(sheetWidth-qrSide) / 2
+
+	// Content is drawn in millimetres from the bottom-left corner.
+	var c bytes.Buffer
+	fmt.Fprintf(&c, "%.5f 0 0 %.5f 0 0 cm\n", ptPerMM, ptPerMM)
+	fmt.Fprintf(&c, "0.3 w 0 0 %d %d re S\n", sheetWidth, sheetHeight)
+	pdfText(&c, fontBold, 7, 16, s.MerchantName)
+	pdfText(&c, fontRegular, 4, 23, s.City)
+
+	fmt.Fprintf(&c, "q %.4f 0 0 %.4f %.2f %.2f cm\n", scale, scale, qrX, float64(sheetHeight-28)-qrSide)
+	for y := 0; y < sym.Size(); y++ {
+		for x := 0; x < sym.Size(); x++ {
+			if sym.Module(x, y) {
+				fmt.Fprintf(&c, "%d %d 1 1 re\n", x+qrcode.DefaultQuietZone, units-1-(y+qrcode.DefaultQuietZone))
+			}
+		}
+	}
+	c.WriteString("f Q\n")
+
+	pdfText(&c, fontBold, 8, 120, s.Alias)
+	pdfText(&c, fontRegular, 4, 128, strings.ReplaceAll(s.Provider, "_", " "))
+	if s.Amount.IsPositive() {
+		pdfText(&c, fontRegular, 4, 134, "MWK "+s.Amount.String())
+	}
+	pdfText(&c, fontMono, 3.5, 142, "Code: "+s.VerificationCode)
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /%s 4 0 R /%s 5 0 R /%s 6 0 R >> >> /Contents 7 0 R >>",
+			sheetWidth*ptPerMM, sheetHeight*ptPerMM, fontRegular, fontBold, fontMono),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", c.Len(), c.String()),
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
+
+// pdfText writes one line centred on the sheet with its baseline top mm
+// from the top edge, matching the SVG layout.
+func pdfText(c *bytes.Buffer, font string, size, top float64, s string) {
+	s = pdfASCII(s)
+	x := (sheetWidth - textWidth(font, s)*size) / 2
+	fmt.Fprintf(c, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, sheetHeight-top, pdfEscape(s))
+}
+
+// textWidth returns the width of s in ems.
+func textWidth(font, s string) float64 {
+	total := 0
+	for i := 0; i < len(s); i++ {
+		switch font {
+		case fontMono:
+			total += 600
+		case fontBold:
+			total += helveticaBoldWidths[s[i]-32]
+		default:
+			total += helveticaWidths[s[i]-32]
+		}
+	}
+	return float64(total) / 1000
+}
+
+// pdfASCII replaces characters the base-14 metrics above do not cover.
+func pdfASCII(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 32 || r > 126 {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(s)
+}
@@ -31,48 +31,42 @@ func main() {
 
 	// 2. Merchant (Cafe) Generates a UMQR for a student to scan
 	fmt.Println("\n[Cafe] Generating Dynamic UMQR for Lunch...")
-	lunchAmount := 2500.00
-	qr := umqr.GenerateMerchantQR("MUBAS Cafeteria", "Blantyre", "@mubas_cafe", "AIRTEL_MONEY", lunchAmount, "LUNCH-45")
+	lunchAmount := mwjson.Kwacha(2500)
+	qr, err := umqr.GenerateDynamicQR(umqr.DynamicQR{
+		MerchantName: "MUBAS Cafeteria",
+		City:         "Blantyre",
+		Alias:        "@mubas_cafe",
+		Provider:     "AIRTEL_MONEY",
+		MCC:          umqr.MCCCampusCanteen,
+		Amount:       lunchAmount,
+		Reference:    "LUNCH-45",
+		Expiry:       time.Now().Add(5 * time.Minute),
+	})
+	if err != nil {
+		log.Fatalf("UMQR Error: %v", err)
+	}
 	fmt.Printf("Produced UMQR: %s\n", qr)
 
-	// 3. Student scans QR and resolves the alias to find where to send money
-	fmt.Println("\n[Student App] Resolving @mubas_cafe via ALS...")
-	res, err := als.Resolve(context.Background(), "@mubas_cafe")
+	// 3. Student scans QR
+	scanned, err := umqr.Decode(qr)
 	if err != nil {
-		log.Fatalf("ALS Error: %v", err)
+		log.Fatalf("Scan Error: %v", err)
 	}
-	fmt.Printf("Resolved to: %s (Provider: %s)\n", res.IdentityMask, res.Endpoints[0].Provider)
 
-	// 4. Student App constructs a MW-JSON Transaction
-	fmt.Println("\n[Student App] Constructing MW-JSON Transaction...")
+	// 4. Student App resolves the alias via ALS and drafts a MW-JSON Transaction
+	fmt.Println("\n[Student App] Resolving @mubas_cafe via ALS and drafting MW-JSON Transaction...")
 	pubKey, privKey, _ := ed25519.GenerateKey(rand.Reader) // In reality, keys are stored on device
 
-	tx := &mwjson.Transaction{
-		MWVersion: mwjson.MWJSONVersion,
-		Header: mwjson.Header{
-			MsgID:          "TXN-STU-99",
-			Timestamp:      time.Now().UTC(),
-			TTL:            300,
-			IdempotencyKey: "unique-stu-12345",
-		},
-		Payload: mwjson.Payload{
-			Amount:   lunchAmount,
-			Currency: mwjson.CurrencyMWK,
-			Type:     mwjson.TxTypeC2B,
-			Sender: mwjson.Participant{
-				ID:       "265991234567",
-				IDType:   mwjson.IDTypeMSISDN,
-				Provider: mwjson.ProviderAirtelMoney,
-				Alias:    "@student_john",
-			},
-			Receiver: mwjson.Participant{
-				ID:       res.Endpoints[0].Destination,
-				IDType:   mwjson.IDTypeMSISDN,
-				Provider: mwjson.Provider(res.Endpoints[0].Provider),
-				Alias:    res.Alias,
-			},
-		},
+	tx, err := umqr.DraftTransaction(context.Background(), scanned, als, mwjson.Participant{
+		ID:       "265991234567",
+		IDType:   mwjson.IDTypeMSISDN,
+		Provider: mwjson.ProviderAirtelMoney,
+		Alias:    "@student_john",
+	})
+	if err != nil {
+		log.Fatalf("Draft Error: %v", err)
 	}
+	fmt.Printf("Paying %s (Provider: %s), MsgID %s\n", tx.Payload.Receiver.Alias, tx.Payload.Receiver.Provider, tx.Header.MsgID)
 
 	// 5. Student signs the transaction
 	err = tx.SignTransaction(privKey)
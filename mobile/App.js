@@ -29,6 +29,12 @@ export default function App() {
   const handleScan = (data) => {
     try {
       const parsed = parseMalawiQR(data);
+      if (parsed.amount === null) {
+        // Open-amount codes need an amount entry screen, which the app lacks.
+        Alert.alert("Amount Required", "This code has no amount. Ask the recipient for a code with an amount.");
+        setCurrentScreen('home');
+        return;
+      }
       setTxnDetails(parsed);
       setCurrentScreen('payment');
     } catch (e) {
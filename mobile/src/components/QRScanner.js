@@ -12,7 +12,7 @@ const QRScanner = ({ onRead, onClose }) => {
 
                 <TouchableOpacity
                     style={styles.simulateButton}
-                    onPress={() => onRead("mw:1.0:TXN-MOCK:...")}
+                    onPress={() => onRead("mw:1.0:TXN:AIRTEL_MONEY:@mubas_cafe:2500")}
                 >
                     <Text style={styles.simulateButtonText}>Simulate Scan</Text>
                 </TouchableOpacity>
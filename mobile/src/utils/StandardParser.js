@@ -5,28 +5,36 @@
  */
 
 export const parseMalawiQR = (rawTagData) => {
-    // Simplified TLV Parsing logic from pkg/umqr
-    // Real implementation would loop through the string parsing Tags and Lengths
-
-    // Mock logic to show structure:
+    // Compact "mw:" URI, see pkg/mwuri:
+    // mw:1.0:TXN:AIRTEL_MONEY:@chifundo:5000[:SIG]
+    // Text fields are percent-encoded; SIG is base64url Ed25519 over the
+    // part before it.
     if (!rawTagData.startsWith("mw:")) {
         throw new Error("Invalid Standard Prefix");
     }
 
-    // Example expected structure: mw:1.0:TXN:AIRTEL:0999123456:5000:SIG...
-    const parts = rawTagData.split(":");
+    const parts = rawTagData.trim().split(":");
 
-    if (parts.length < 6) {
+    if (parts.length !== 6 && parts.length !== 7) {
         throw new Error("Malformed QR Data");
     }
 
+    const [, version, type, provider, recipient] = parts.map(decodeURIComponent);
+    if (version.split(".")[0] !== "1") {
+        throw new Error(`Unsupported Version ${version}`);
+    }
+    if (parts[5] !== "" && !/^\d+(\.\d{1,2})?$/.test(parts[5])) {
+        throw new Error("Invalid Amount");
+    }
+
     return {
-        version: parts[1],
-        type: parts[2], // e.g., TXN
-        provider: parts[3], // e.g., AIRTEL_MONEY
-        recipient: parts[4],
-        amount: parseFloat(parts[5]),
+        version,
+        type, // e.g., TXN
+        provider, // e.g., AIRTEL_MONEY
+        recipient,
+        amount: parts[5] === "" ? null : parseFloat(parts[5]), // null: payer enters it
         signature: parts[6] || null,
+        signedContent: parts.slice(0, 6).join(":"),
     };
 };
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/ed25519"
 	"crypto/rand"
+	"encoding/hex"
 	"strings"
 	"testing"
 
@@ -143,3 +144,32 @@ func TestBlindResolution(t *testing.T) {
 		t.Errorf("expected destination to be a TOKEN, got %s", resp.Endpoints[0].Destination)
 	}
 }
+
+func TestRegisterPublicKey(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	svc, _ := mwals.NewService(key, "")
+
+	ownerPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	record := &mwals.AliasRecord{
+		Alias:        "keyed_user",
+		Status:       mwals.AliasStatusActive,
+		IdentityMask: "K**** U***",
+		PublicKey:    hex.EncodeToString(ownerPub),
+	}
+	if err := svc.Register(context.Background(), record); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	resp, err := svc.Resolve(context.Background(), "@keyed_user")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if resp.PublicKey != record.PublicKey {
+		t.Errorf("Expected published key %s, got %s", record.PublicKey, resp.PublicKey)
+	}
+
+	bad := &mwals.AliasRecord{Alias: "bad_key", PublicKey: "not-hex"}
+	if err := svc.Register(context.Background(), bad); err == nil {
+		t.Error("expected error for malformed public key, got nil")
+	}
+}
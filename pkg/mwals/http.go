@@ -63,6 +63,7 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		Attestation:  AttestationUnverified,
 		Endpoints:    req.Endpoints,
 		IsPrivate:    req.IsPrivate,
+		PublicKey:    req.PublicKey,
 	}
 
 	if err := h.resolver.Register(r.Context(), record); err != nil {
@@ -50,6 +50,8 @@ type AliasRecord struct {
 	VerificationProof string
 	// IsPrivate indicates that endpoints should be returned as signed tokens (Blind Resolution)
 	IsPrivate bool
+	// PublicKey is the owner's hex Ed25519 key, used to verify signed QRs and transactions
+	PublicKey string
 }
 
 func NewService(key ed25519.PrivateKey, dataPath string) (*Service, error) {
@@ -112,6 +114,7 @@ func (s *Service) Resolve(ctx context.Context, alias string) (*ResolutionRespons
 		Alias:               "@" + record.Alias,
 		Status:              record.Status,
 		IdentityMask:        record.IdentityMask,
+		PublicKey:           record.PublicKey,
 		ResolutionTimestamp: time.Now().UTC(),
 		Endpoints:           make([]Endpoint, len(record.Endpoints)),
 	}
@@ -125,7 +128,7 @@ func (s *Service) Resolve(ctx context.Context, alias string) (*ResolutionRespons
 			payload := fmt.Sprintf("%s|%s|%s", ep.Provider, ep.Destination, expiry)
 			sig := ed25519.Sign(s.signingKey, []byte(payload))
 
-			resp.Endpoints[i].Destination = fmt.Sprintf("TOKEN:%s:%s", hex.EncodeToString(sig), expiry)
+			resp.Endpoints[i].Destination = fmt.Sprintf("%s%s:%s", TokenPrefix, hex.EncodeToString(sig), expiry)
 		}
 	}
 
@@ -141,20 +144,44 @@ func (s *Service) Resolve(ctx context.Context, alias string) (*ResolutionRespons
 
 func (s *Service) signResponse(resp *ResolutionResponse) (string, error) {
 	if s.signingKey == nil {
-		return "unsigned", nil
+		return Unsigned, nil
 	}
 
-	// Create canonical string for signing
-	// format: alias|status|timestamp|endpoint_count
+	sig := ed25519.Sign(s.signingKey, []byte(resolutionCanonical(resp)))
+	return hex.EncodeToString(sig), nil
+}
+
+// resolutionCanonical builds the string SecuritySig covers.
+// format: alias|status|timestamp|endpoint_count[|public_key]
+func resolutionCanonical(resp *ResolutionResponse) string {
 	canonical := fmt.Sprintf("%s|%s|%s|%d",
 		resp.Alias,
 		resp.Status,
-		resp.ResolutionTimestamp.Format(time.RFC3339),
+		resp.ResolutionTimestamp.UTC().Format(time.RFC3339),
 		len(resp.Endpoints),
 	)
+	if resp.PublicKey != "" {
+		// Bind the published key so it cannot be swapped in transit
+		canonical += "|" + resp.PublicKey
+	}
+	return canonical
+}
 
-	sig := ed25519.Sign(s.signingKey, []byte(canonical))
-	return hex.EncodeToString(sig), nil
+// VerifyResolution checks a response's SecuritySig against the registry's
+// public key. Responses from a Service without a signing key carry
+// Unsigned and are rejected.
+func VerifyResolution(resp *ResolutionResponse, registryKey ed25519.PublicKey) error {
+	if resp.SecuritySig == "" || resp.SecuritySig == Unsigned {
+		return fmt.Errorf("resolution for %s is unsigned", resp.Alias)
+	}
+	sig, err := hex.DecodeString(resp.SecuritySig)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("resolution for %s has a malformed signature", resp.Alias)
+	}
+	if len(registryKey) != ed25519.PublicKeySize || !ed25519.Verify(registryKey, []byte(resolutionCanonical(resp)), sig) {
+		return fmt.Errorf("resolution for %s failed signature verification", resp.Alias)
+	}
+	return nil
 }
 
 // Seed adds a record to the mock store.
@@ -169,6 +196,11 @@ func (s *Service) Register(ctx context.Context, record *AliasRecord) error {
 	if IsReserved(record.Alias) {
 		return fmt.Errorf("alias is reserved: %s", record.Alias)
 	}
+	if record.PublicKey != "" {
+		if key, err := hex.DecodeString(record.PublicKey); err != nil || len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid public key for alias: %s", record.Alias)
+		}
+	}
 
 	clean := Normalizer(record.Alias)
 
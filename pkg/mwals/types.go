@@ -19,6 +19,12 @@ const (
 	EndpointTypeBankAccount EndpointType = "BANK_ACCOUNT"
 )
 
+// Unsigned is the SecuritySig of responses from a registry with no signing key.
+const Unsigned = "unsigned"
+
+// TokenPrefix marks an endpoint destination blinded for a private alias.
+const TokenPrefix = "TOKEN:"
+
 // Endpoint represents a specific financial destination for an alias.
 type Endpoint struct {
 	Priority         int          `json:"priority"`
@@ -33,6 +39,7 @@ type ResolutionResponse struct {
 	Alias               string      `json:"alias"`
 	Status              AliasStatus `json:"status"`
 	IdentityMask        string      `json:"identity_mask"`
+	PublicKey           string      `json:"public_key,omitempty"` // Hex Ed25519 key of the alias owner
 	ResolutionTimestamp time.Time   `json:"resolution_timestamp"`
 	Endpoints           []Endpoint  `json:"endpoints"`
 	SecuritySig         string      `json:"security_sig"`
@@ -53,4 +60,5 @@ type RegistrationRequest struct {
 	IdentityMask string     `json:"identity_mask"`
 	Endpoints    []Endpoint `json:"endpoints"`
 	IsPrivate    bool       `json:"is_private"`
+	PublicKey    string     `json:"public_key,omitempty"`
 }
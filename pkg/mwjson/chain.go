@@ -0,0 +1,154 @@
+package mwjson
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// chainDocument is what a countersignature covers: the transaction, the
+// sender's signature, every earlier countersignature and the new entry's
+// own role, key ID and timestamp.
+type chainDocument struct {
+	MWVersion string             `json:"mw_version"`
+	Header    json.RawMessage    `json:"header"`
+	Payload   json.RawMessage    `json:"payload"`
+	Scheme    string             `json:"scheme"`
+	Signature string             `json:"signature"`
+	Chain     []Countersignature `json:"chain"`
+	Entry     Countersignature   `json:"entry"`
+}
+
+// countersigningInput returns the bytes countersignature i covers. Like
+// the sender's signature it covers the header and payload as received.
+func (t *Transaction) countersigningInput(i int) ([]byte, error) {
+	header, payload, err := t.wireParts()
+	if err != nil {
+		return nil, err
+	}
+	entry := t.TrustLayer.Countersignatures[i]
+	entry.Timestamp = entry.Timestamp.UTC()
+	entry.Signature = ""
+
+	chain := make([]Countersignature, i)
+	for j, c := range t.TrustLayer.Countersignatures[:i] {
+		c.Timestamp = c.Timestamp.UTC()
+		chain[j] = c
+	}
+	return CanonicalJSON(chainDocument{
+		MWVersion: t.MWVersion,
+		Header:    header,
+		Payload:   payload,
+		Scheme:    t.TrustLayer.Scheme,
+		Signature: t.TrustLayer.Signature,
+		Chain:     chain,
+		Entry:     entry,
+	})
+}
+
+// Countersign appends a countersignature for the given role, covering the
+// transaction, the sender's signature and all earlier countersignatures.
+// keyID should name privateKey so verifiers can find it, e.g. ALSKeyID.
+// The timestamp is recorded in whole seconds.
+func (t *Transaction) Countersign(role Role, keyID string, privateKey ed25519.PrivateKey) error {
+	if t.TrustLayer.Signature == "" {
+		return NewMWError(ErrInvalidSignature, "Cannot Countersign Unsigned Transaction", "")
+	}
+	if !validRole(role) {
+		return NewMWError(ErrSchemaValidation, "Invalid Countersignature Role", string(role))
+	}
+	if keyID == "" {
+		return NewMWError(ErrSchemaValidation, "Missing Key ID", "Countersignatures must name their key")
+	}
+
+	// Whole seconds, so the timestamp survives the protobuf encoding,
+	// which carries Unix seconds
+	now := time.Now().UTC().Truncate(time.Second)
+	if n := len(t.TrustLayer.Countersignatures); n > 0 {
+		if last := t.TrustLayer.Countersignatures[n-1].Timestamp; now.Before(last) {
+			now = last
+		}
+	}
+	t.TrustLayer.Countersignatures = append(t.TrustLayer.Countersignatures, Countersignature{
+		Role:      role,
+		KeyID:     keyID,
+		Timestamp: now,
+	})
+
+	i := len(t.TrustLayer.Countersignatures) - 1
+	msg, err := t.countersigningInput(i)
+	if err != nil {
+		t.TrustLayer.Countersignatures = t.TrustLayer.Countersignatures[:i]
+		return NewMWError(ErrInternalError, "Canonicalization Failed", err.Error())
+	}
+	t.TrustLayer.Countersignatures[i].Signature = hex.EncodeToString(ed25519.Sign(privateKey, msg))
+	return nil
+}
+
+// VerifyCountersignature checks countersignature i against publicKey. It
+// does not check the signatures before it; use VerifyChain for that.
+func (t *Transaction) VerifyCountersignature(i int, publicKey ed25519.PublicKey) error {
+	if i < 0 || i >= len(t.TrustLayer.Countersignatures) {
+		return NewMWError(ErrInvalidSignature, "No Such Countersignature", fmt.Sprintf("index %d", i))
+	}
+	c := t.TrustLayer.Countersignatures[i]
+	details := fmt.Sprintf("countersignature %d (%s, %s)", i, c.Role, c.KeyID)
+
+	sigBytes, err := hex.DecodeString(c.Signature)
+	if err != nil || len(sigBytes) != ed25519.SignatureSize {
+		return NewMWError(ErrInvalidSignature, "Invalid Countersignature Format", details)
+	}
+	msg, err := t.countersigningInput(i)
+	if err != nil {
+		return NewMWError(ErrInternalError, "Canonicalization Failed", err.Error())
+	}
+	if !ed25519.Verify(publicKey, msg, sigBytes) {
+		return NewMWError(ErrInvalidSignature, "Countersignature Verification Failed", details)
+	}
+	return nil
+}
+
+// VerifyChain checks the sender's signature and then every countersignature
+// in order, looking up each key through r. The first failure is returned,
+// naming the hop it belongs to.
+//
+// VerifyChain proves which key signed each entry, not that the key's owner
+// holds the Role it claims. Callers that rely on roles must check each
+// KeyID against their own list of gateways, providers and switches.
+func (t *Transaction) VerifyChain(ctx context.Context, r KeyResolver) error {
+	if err := t.VerifyWithResolver(ctx, r); err != nil {
+		return err
+	}
+
+	var prev time.Time
+	for i, c := range t.TrustLayer.Countersignatures {
+		details := fmt.Sprintf("countersignature %d (%s, %s)", i, c.Role, c.KeyID)
+		if !validRole(c.Role) {
+			return NewMWError(ErrSchemaValidation, "Invalid Countersignature Role", details)
+		}
+		if c.Timestamp.Before(prev) {
+			return NewMWError(ErrInvalidSignature, "Countersignatures Out Of Order", details)
+		}
+		prev = c.Timestamp
+
+		key, err := r.ResolveKey(ctx, c.KeyID)
+		if err != nil {
+			return err
+		}
+		if err := t.VerifyCountersignature(i, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validRole(r Role) bool {
+	switch r {
+	case RolePayer, RoleGateway, RoleProvider, RoleSwitch:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,363 @@
+package mwjson
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MinIdempotencyTTL is the shortest time a record is kept. A record is
+// kept for the longer of Header.TTL and this floor, so for typical TTLs of
+// a few minutes retention is effectively 24 hours. Header.TTL decides how
+// long a request may reach the provider; the floor decides how long a
+// retry, e.g. from a phone that was offline, is still recognised.
+const MinIdempotencyTTL = 24 * time.Hour
+
+// IdempotencyRecord is what a store keeps for one idempotency key.
+type IdempotencyRecord struct {
+	Key         string    `json:"key"`
+	Fingerprint string    `json:"fingerprint"`      // SHA-256 of the canonical payload
+	MsgID       string    `json:"msg_id,omitempty"` // Of the call that reserved the key, for QueryStatus
+	Completed   bool      `json:"completed"`
+	Result      string    `json:"result,omitempty"` // Provider transaction ID
+	ExpiresAt   time.Time `json:"expires_at"`       // Receipt plus the longer of Header.TTL and MinIdempotencyTTL
+}
+
+// IdempotencyStore remembers which requests have been seen until their
+// records expire (see MinIdempotencyTTL). Implementations must make Reserve atomic, so that two retries
+// racing each other cannot both reach the provider.
+type IdempotencyStore interface {
+	// Reserve stores rec unless an unexpired record with the same key
+	// exists. It returns that existing record, or nil if rec was stored.
+	Reserve(ctx context.Context, rec IdempotencyRecord) (*IdempotencyRecord, error)
+
+	// Complete records the result of a reserved request.
+	Complete(ctx context.Context, key, result string) error
+
+	// Release forgets a reservation so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryIdempotencyStore is an in-process IdempotencyStore. Expired records
+// are evicted whenever a new key is reserved.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*IdempotencyRecord
+}
+
+// NewMemoryIdempotencyStore creates an empty in-memory store.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]*IdempotencyRecord)}
+}
+
+// Reserve implements the IdempotencyStore interface.
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, rec IdempotencyRecord) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, _ := s.reserve(rec, time.Now())
+	return existing, nil
+}
+
+// reserve does the work of Reserve with s.mu held. changed reports whether
+// the records map was modified.
+func (s *MemoryIdempotencyStore) reserve(rec IdempotencyRecord, now time.Time) (existing *IdempotencyRecord, changed bool) {
+	for k, r := range s.records {
+		if now.After(r.ExpiresAt) {
+			delete(s.records, k)
+			changed = true
+		}
+	}
+	if r, ok := s.records[rec.Key]; ok {
+		copied := *r
+		return &copied, changed
+	}
+	s.records[rec.Key] = &rec
+	return nil, true
+}
+
+// Complete implements the IdempotencyStore interface.
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key, result string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.complete(key, result)
+}
+
+func (s *MemoryIdempotencyStore) complete(key, result string) error {
+	r, ok := s.records[key]
+	if !ok {
+		return NewMWError(ErrInternalError, "Idempotency Key Not Reserved", key)
+	}
+	r.Completed = true
+	r.Result = result
+	return nil
+}
+
+// Release implements the IdempotencyStore interface.
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// FileIdempotencyStore is an IdempotencyStore persisted as a JSON file, so
+// a restarted gateway still recognises retries of requests it already
+// passed on. It suits a single node; clusters need a shared store.
+type FileIdempotencyStore struct {
+	MemoryIdempotencyStore
+	path string
+}
+
+// NewFileIdempotencyStore opens the store at path, loading any records
+// already there.
+func NewFileIdempotencyStore(path string) (*FileIdempotencyStore, error) {
+	s := &FileIdempotencyStore{
+		MemoryIdempotencyStore: MemoryIdempotencyStore{records: make(map[string]*IdempotencyRecord)},
+		path:                   path,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, NewMWError(ErrInternalError, "Failed To Load Idempotency Store", err.Error())
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.records); err != nil {
+			return nil, NewMWError(ErrInternalError, "Corrupt Idempotency Store", err.Error())
+		}
+	}
+	return s, nil
+}
+
+// save writes the records with s.mu held. It writes a temporary file and
+// renames it, so a crash never leaves a half-written store.
+func (s *FileIdempotencyStore) save() error {
+	data, err := json.Marshal(s.records)
+	if err != nil {
+		return NewMWError(ErrInternalError, "Failed To Save Idempotency Store", err.Error())
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return NewMWError(ErrInternalError, "Failed To Save Idempotency Store", err.Error())
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return NewMWError(ErrInternalError, "Failed To Save Idempotency Store", err.Error())
+	}
+	// Flush before the rename, or a power cut can leave an empty store
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return NewMWError(ErrInternalError, "Failed To Save Idempotency Store", err.Error())
+	}
+	if err := tmp.Close(); err != nil {
+		return NewMWError(ErrInternalError, "Failed To Save Idempotency Store", err.Error())
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return NewMWError(ErrInternalError, "Failed To Save Idempotency Store", err.Error())
+	}
+	return nil
+}
+
+// Reserve implements the IdempotencyStore interface.
+func (s *FileIdempotencyStore) Reserve(ctx context.Context, rec IdempotencyRecord) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, changed := s.reserve(rec, time.Now())
+	if changed {
+		if err := s.save(); err != nil {
+			if existing == nil {
+				delete(s.records, rec.Key)
+			}
+			return nil, err
+		}
+	}
+	return existing, nil
+}
+
+// Complete implements the IdempotencyStore interface.
+func (s *FileIdempotencyStore) Complete(ctx context.Context, key, result string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.complete(key, result); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// Release implements the IdempotencyStore interface.
+func (s *FileIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return s.save()
+}
+
+// IdempotentProvider wraps a PaymentProvider so that a retried Authorize or
+// Transfer returns the original result instead of reaching the provider
+// again. A reused idempotency key with a different payload fails with
+// ErrDuplicateTx. Calls the provider definitely rejected are released, so
+// they can be retried. Any other failure, such as a timeout, may still have
+// moved money: the key stays reserved and a retry asks the provider for the
+// outcome through QueryStatus.
+type IdempotentProvider struct {
+	next  PaymentProvider
+	store IdempotencyStore
+}
+
+// NewIdempotentProvider wraps next with store.
+// e.g., mwjson.RegisterProvider(mwjson.ProviderAirtelMoney, mwjson.NewIdempotentProvider(&AirtelAdapter{}, store))
+func NewIdempotentProvider(next PaymentProvider, store IdempotencyStore) *IdempotentProvider {
+	return &IdempotentProvider{next: next, store: store}
+}
+
+// Authorize implements the PaymentProvider interface.
+func (p *IdempotentProvider) Authorize(ctx context.Context, entry *Transaction) (string, error) {
+	return p.do(ctx, "authorize", entry, p.next.Authorize)
+}
+
+// Transfer implements the PaymentProvider interface.
+func (p *IdempotentProvider) Transfer(ctx context.Context, entry *Transaction) (string, error) {
+	return p.do(ctx, "transfer", entry, p.next.Transfer)
+}
+
+// QueryStatus implements the PaymentProvider interface. Queries are
+// naturally idempotent and pass straight through.
+func (p *IdempotentProvider) QueryStatus(ctx context.Context, msgID string) (*TransactionStatus, error) {
+	return p.next.QueryStatus(ctx, msgID)
+}
+
+// do reserves the request's key and makes the call. If the call succeeds
+// but the result cannot be stored, do returns the result together with the
+// store error: the money has moved, and a retry settles the record through
+// QueryStatus.
+func (p *IdempotentProvider) do(ctx context.Context, op string, tx *Transaction, call func(context.Context, *Transaction) (string, error)) (string, error) {
+	if tx.Header.IdempotencyKey == "" {
+		return "", NewMWError(ErrSchemaValidation, "Missing Idempotency Key", "")
+	}
+	if tx.Header.TTL <= 0 {
+		return "", NewMWError(ErrSchemaValidation, "Invalid TTL", "Must be positive integer")
+	}
+	if time.Since(tx.Header.Timestamp) > time.Duration(tx.Header.TTL)*time.Second {
+		return "", NewMWError(ErrGhostTransaction, "Transaction Expired", "TTL exceeded")
+	}
+	fp, err := requestFingerprint(tx)
+	if err != nil {
+		return "", err
+	}
+
+	// Keys are per operation, so Authorize then Transfer is not a
+	// duplicate, and per sender, so two clients cannot collide. Records
+	// expire by the gateway's clock, not the client's timestamp, and never
+	// before MinIdempotencyTTL.
+	rec := IdempotencyRecord{
+		Key:         op + "/" + tx.Payload.Sender.ID + "/" + tx.Header.IdempotencyKey,
+		Fingerprint: fp,
+		MsgID:       tx.Header.MsgID,
+		ExpiresAt:   time.Now().Add(max(time.Duration(tx.Header.TTL)*time.Second, MinIdempotencyTTL)),
+	}
+	existing, err := p.store.Reserve(ctx, rec)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil && existing.Fingerprint == fp && !existing.Completed {
+		if existing, err = p.settle(ctx, existing); err != nil {
+			return "", err
+		}
+		if existing == nil {
+			// The provider reports the original call failed
+			if existing, err = p.store.Reserve(ctx, rec); err != nil {
+				return "", err
+			}
+		}
+	}
+	if existing != nil {
+		if existing.Fingerprint != fp {
+			return "", NewMWError(ErrDuplicateTx, "Idempotency Key Reused", "Same key with a different payload")
+		}
+		if !existing.Completed {
+			return "", NewMWError(ErrDuplicateTx, "Duplicate Request In Progress", tx.Header.IdempotencyKey)
+		}
+		return existing.Result, nil
+	}
+
+	result, err := call(ctx, tx)
+	if err != nil {
+		if rejected(err) {
+			// If the release fails the key stays reserved and a retry
+			// settles it through QueryStatus.
+			_ = p.store.Release(ctx, rec.Key)
+		}
+		return "", err
+	}
+	if err := p.store.Complete(ctx, rec.Key, result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// settle asks the provider how the call that reserved rec ended. It
+// returns the completed record on success, nil after releasing a failed
+// call, and ErrDuplicateTx while the outcome is still unknown.
+func (p *IdempotentProvider) settle(ctx context.Context, rec *IdempotencyRecord) (*IdempotencyRecord, error) {
+	inProgress := NewMWError(ErrDuplicateTx, "Duplicate Request In Progress", rec.Key)
+	if rec.MsgID == "" {
+		return nil, inProgress
+	}
+	status, err := p.next.QueryStatus(ctx, rec.MsgID)
+	if err != nil || status == nil {
+		return nil, inProgress
+	}
+
+	switch status.Status {
+	case "SUCCESS":
+		result := status.TransactionID
+		if result == "" {
+			result = status.MsgID
+		}
+		if err := p.store.Complete(ctx, rec.Key, result); err != nil {
+			return nil, err
+		}
+		settled := *rec
+		settled.Completed = true
+		settled.Result = result
+		return &settled, nil
+	case "FAILED":
+		if err := p.store.Release(ctx, rec.Key); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return nil, inProgress
+}
+
+// rejected reports whether err means the provider refused the request, so
+// no money moved. Timeouts, transport errors and outages are not
+// rejections: the provider may have acted before the connection dropped.
+func rejected(err error) bool {
+	var mwErr *MWError
+	if !errors.As(err, &mwErr) {
+		return false
+	}
+	switch mwErr.Code {
+	case ErrInsufficientFunds, ErrSchemaValidation, ErrInvalidSignature, ErrUnauthorized, ErrAliasNotFound:
+		return true
+	}
+	return false
+}
+
+// requestFingerprint hashes only the payload: a client retrying with a new
+// msg_id or timestamp is still making the same request.
+func requestFingerprint(tx *Transaction) (string, error) {
+	doc, err := CanonicalJSON(tx.Payload)
+	if err != nil {
+		return "", NewMWError(ErrInternalError, "Canonicalization Failed", err.Error())
+	}
+	sum := sha256.Sum256(doc)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -22,9 +22,10 @@ type PaymentProvider interface {
 
 // TransactionStatus holds the result of a status query
 type TransactionStatus struct {
-	MsgID   string                 `json:"msg_id"`
-	Status  string                 `json:"status"` // PENDING, SUCCESS, FAILED
-	RawData map[string]interface{} `json:"raw_data,omitempty"`
+	MsgID         string                 `json:"msg_id"`
+	Status        string                 `json:"status"`                   // PENDING, SUCCESS, FAILED
+	TransactionID string                 `json:"transaction_id,omitempty"` // As Authorize or Transfer would have returned it
+	RawData       map[string]interface{} `json:"raw_data,omitempty"`
 }
 
 // ProviderRegistry manages the available payment providers.
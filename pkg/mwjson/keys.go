@@ -0,0 +1,97 @@
+package mwjson
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"strings"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwals"
+)
+
+// ALSKeyPrefix marks key IDs that name the key an alias publishes in MW-ALS,
+// e.g. "als:@john".
+const ALSKeyPrefix = "als:"
+
+// KeyResolver looks up the Ed25519 public key behind a TrustLayer.KeyID.
+type KeyResolver interface {
+	ResolveKey(ctx context.Context, keyID string) (ed25519.PublicKey, error)
+}
+
+// ALSKeyID returns the key ID for the key published by alias in MW-ALS.
+func ALSKeyID(alias string) string {
+	return ALSKeyPrefix + "@" + mwals.Normalizer(alias)
+}
+
+// ALSKeyResolver resolves "als:" key IDs to the public key the alias owner
+// registered in MW-ALS. Only responses carrying a valid SecuritySig from
+// the registry are trusted.
+type ALSKeyResolver struct {
+	resolver    mwals.Resolver
+	registryKey ed25519.PublicKey
+}
+
+// NewALSKeyResolver creates a KeyResolver backed by an MW-ALS resolver,
+// such as an mwals.Client pointed at the national registry, and the
+// registry's Ed25519 public key used to check its responses.
+func NewALSKeyResolver(r mwals.Resolver, registryKey ed25519.PublicKey) *ALSKeyResolver {
+	return &ALSKeyResolver{resolver: r, registryKey: registryKey}
+}
+
+// ResolveKey implements the KeyResolver interface.
+func (r *ALSKeyResolver) ResolveKey(ctx context.Context, keyID string) (ed25519.PublicKey, error) {
+	alias, ok := strings.CutPrefix(keyID, ALSKeyPrefix)
+	if !ok || alias == "" {
+		return nil, NewMWError(ErrInvalidSignature, "Unsupported Key ID", keyID)
+	}
+
+	res, err := r.resolver.Resolve(ctx, alias)
+	if err != nil {
+		return nil, NewMWError(ErrAliasNotFound, "Key Owner Not Found", err.Error())
+	}
+	if err := mwals.VerifyResolution(res, r.registryKey); err != nil {
+		return nil, NewMWError(ErrInvalidSignature, "Untrusted Registry Response", err.Error())
+	}
+	if mwals.Normalizer(res.Alias) != mwals.Normalizer(alias) {
+		return nil, NewMWError(ErrInvalidSignature, "Registry Response For Wrong Alias", res.Alias)
+	}
+	if res.Status != mwals.AliasStatusActive {
+		return nil, NewMWError(ErrUnauthorized, "Key Owner Not Active", string(res.Status))
+	}
+	if res.PublicKey == "" {
+		return nil, NewMWError(ErrInvalidSignature, "No Published Key", res.Alias)
+	}
+	key, err := hex.DecodeString(res.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, NewMWError(ErrInvalidSignature, "Malformed Published Key", res.Alias)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// VerifyWithResolver looks up the signer's key and checks the signature.
+// Without a TrustLayer.KeyID the sender's alias key is used. The key ID
+// must be an "als:" ID naming the sender's own alias, so nobody can sign
+// for a sender with the key of an alias they control; other key IDs carry
+// no proof of ownership and are rejected.
+func (t *Transaction) VerifyWithResolver(ctx context.Context, r KeyResolver) error {
+	keyID := t.TrustLayer.KeyID
+	if keyID == "" {
+		if t.Payload.Sender.Alias == "" {
+			return NewMWError(ErrInvalidSignature, "Missing Key ID", "No key_id and no sender alias")
+		}
+		keyID = ALSKeyID(t.Payload.Sender.Alias)
+	}
+	alias, ok := strings.CutPrefix(keyID, ALSKeyPrefix)
+	if !ok {
+		return NewMWError(ErrInvalidSignature, "Unsupported Key ID", keyID)
+	}
+	if t.Payload.Sender.Alias == "" || mwals.Normalizer(alias) != mwals.Normalizer(t.Payload.Sender.Alias) {
+		return NewMWError(ErrInvalidSignature, "Key ID Does Not Match Sender", keyID)
+	}
+
+	key, err := r.ResolveKey(ctx, keyID)
+	if err != nil {
+		return err
+	}
+	return t.VerifySignature(key)
+}
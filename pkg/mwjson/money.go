@@ -0,0 +1,158 @@
+package mwjson
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// TambalaPerKwacha is the number of minor units in one kwacha.
+const TambalaPerKwacha = 100
+
+// Money is an exact MWK amount held in tambala. The zero value is MWK 0.00.
+// It has a struct type so that untyped constants such as 5000 cannot be
+// mistaken for kwacha or tambala; use Kwacha or Tambala.
+type Money struct {
+	tambala int64
+}
+
+// Kwacha returns an amount of whole kwacha.
+func Kwacha(k int64) Money {
+	return Money{tambala: k * TambalaPerKwacha}
+}
+
+// Tambala returns an amount in minor units.
+func Tambala(t int64) Money {
+	return Money{tambala: t}
+}
+
+// ParseMoney parses a decimal amount such as "2500", "2500.5" or "2500.50".
+// More than two decimal places is an error, never a rounding.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ContainsAny(s, "/_") {
+		return Money{}, NewMWError(ErrSchemaValidation, "Invalid Amount", fmt.Sprintf("%q is not a decimal number", s))
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Money{}, NewMWError(ErrSchemaValidation, "Invalid Amount", fmt.Sprintf("%q is not a decimal number", s))
+	}
+	r.Mul(r, big.NewRat(TambalaPerKwacha, 1))
+	if !r.IsInt() {
+		return Money{}, NewMWError(ErrSchemaValidation, "Invalid Amount Precision", "MWK supports up to 2 decimal places")
+	}
+	if !r.Num().IsInt64() {
+		return Money{}, NewMWError(ErrSchemaValidation, "Invalid Amount", "Out of range")
+	}
+	return Money{tambala: r.Num().Int64()}, nil
+}
+
+// Tambala returns the amount in minor units.
+func (m Money) Tambala() int64 {
+	return m.tambala
+}
+
+// Kwacha returns the whole kwacha part, truncated toward zero.
+func (m Money) Kwacha() int64 {
+	return m.tambala / TambalaPerKwacha
+}
+
+// IsWhole reports whether the amount has no tambala part.
+func (m Money) IsWhole() bool {
+	return m.tambala%TambalaPerKwacha == 0
+}
+
+// IsZero reports whether the amount is MWK 0.00.
+func (m Money) IsZero() bool {
+	return m.tambala == 0
+}
+
+// IsPositive reports whether the amount is greater than zero.
+func (m Money) IsPositive() bool {
+	return m.tambala > 0
+}
+
+// Add returns m + o.
+func (m Money) Add(o Money) Money {
+	return Money{tambala: m.tambala + o.tambala}
+}
+
+// Sub returns m - o.
+func (m Money) Sub(o Money) Money {
+	return Money{tambala: m.tambala - o.tambala}
+}
+
+// Cmp returns -1, 0 or +1 as m is less than, equal to or greater than o.
+func (m Money) Cmp(o Money) int {
+	switch {
+	case m.tambala < o.tambala:
+		return -1
+	case m.tambala > o.tambala:
+		return 1
+	}
+	return 0
+}
+
+// Percent returns basisPoints hundredths of a percent of m, e.g. 250 for
+// 2.5%, rounded like MulRat. Percentages never go through a float, so 2.3%
+// of 15.00 is exactly 34.5 tambala and rounds to 0.35.
+func (m Money) Percent(basisPoints int64) (Money, error) {
+	return m.MulRat(big.NewRat(basisPoints, 10000))
+}
+
+// MulRat multiplies the amount by an exact rational, e.g. an FX rate, and
+// rounds half away from zero to the nearest tambala. It fails when the
+// result does not fit.
+func (m Money) MulRat(r *big.Rat) (Money, error) {
+	x := new(big.Rat).Mul(new(big.Rat).SetInt64(m.tambala), r)
+	q, rem := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int))
+	if rem.Sign() != 0 && new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(x.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(x.Sign())))
+	}
+	if !q.IsInt64() {
+		return Money{}, fmt.Errorf("mwjson: amount %s times %s overflows", m, r.RatString())
+	}
+	return Money{tambala: q.Int64()}, nil
+}
+
+// Float64 returns the amount in kwacha for display or legacy APIs. It must
+// not be used for arithmetic.
+func (m Money) Float64() float64 {
+	return float64(m.tambala) / TambalaPerKwacha
+}
+
+// String formats the amount with exactly two decimals, e.g. "2500.00".
+func (m Money) String() string {
+	sign, t := "", m.tambala
+	if t < 0 {
+		sign, t = "-", -t
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, t/TambalaPerKwacha, t%TambalaPerKwacha)
+}
+
+// MarshalJSON writes the amount as a JSON number in kwacha, as float64
+// amounts were written: 2500, 2500.5, 2500.05.
+func (m Money) MarshalJSON() ([]byte, error) {
+	s := m.String()
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	return []byte(s), nil
+}
+
+// UnmarshalJSON reads a JSON number exactly, without passing through float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return NewMWError(ErrSchemaValidation, "Invalid Amount", fmt.Sprintf("%s is not a JSON number", s))
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
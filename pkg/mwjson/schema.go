@@ -17,6 +17,33 @@ type Transaction struct {
 	Header     Header     `json:"header"`
 	Payload    Payload    `json:"payload"`
 	TrustLayer TrustLayer `json:"trust_layer"`
+
+	// received is the header and payload exactly as they were unmarshaled,
+	// so signatures are checked over the sender's bytes; see wireParts.
+	received *receivedJSON
+}
+
+// receivedJSON holds the parts of an unmarshaled transaction that Go
+// would not reproduce byte for byte, e.g. "10:00:00.120Z" or "alias": "".
+type receivedJSON struct {
+	header, payload json.RawMessage
+	keyIDPresent    bool // trust_layer had a key_id member, even ""
+}
+
+// transactionJSON is the wire layout of a Transaction with the header and
+// payload kept as raw JSON.
+type transactionJSON struct {
+	MWVersion  string          `json:"mw_version"`
+	Header     json.RawMessage `json:"header"`
+	Payload    json.RawMessage `json:"payload"`
+	TrustLayer trustLayerJSON  `json:"trust_layer"`
+}
+
+// trustLayerJSON records whether key_id is present, not just its value.
+// The outer KeyID shadows TrustLayer.KeyID.
+type trustLayerJSON struct {
+	TrustLayer
+	KeyID *string `json:"key_id,omitempty"`
 }
 
 // Header contains metadata about the transaction
@@ -29,11 +56,13 @@ type Header struct {
 
 // Payload contains the business logic of the transaction
 type Payload struct {
-	Amount   float64     `json:"amount"` // Validated for precision later
+	Amount   Money       `json:"amount"` // Exact, in tambala; a JSON number in kwacha on the wire
 	Currency string      `json:"currency"`
 	Type     TxType      `json:"type"`
 	Sender   Participant `json:"sender"`
 	Receiver Participant `json:"receiver"`
+	// Reference is the merchant's bill number or reference, for reconciliation.
+	Reference string `json:"reference,omitempty"`
 }
 
 // Participant represents a sender or receiver within the transaction
@@ -48,7 +77,22 @@ type Participant struct {
 type TrustLayer struct {
 	IntegrityHash string `json:"integrity_hash"`
 	KYCVerified   bool   `json:"kyc_verified"`
+	Scheme        string `json:"scheme,omitempty"`    // Signature scheme; empty means SchemeLegacyPipe
+	KeyID         string `json:"key_id,omitempty"`    // Signer's key, e.g. "als:@john"; see KeyResolver
 	Signature     string `json:"extension_signature"` // Ed25519 signature
+
+	// Countersignatures are added by each hop after the sender, in order.
+	Countersignatures []Countersignature `json:"countersignatures,omitempty"`
+}
+
+// Countersignature is one hop's attestation of the transaction and of
+// every signature before it. Role is signed but is the signer's own claim;
+// nothing ties it to the owner of KeyID.
+type Countersignature struct {
+	Role      Role      `json:"role"`
+	KeyID     string    `json:"key_id"`
+	Timestamp time.Time `json:"timestamp"` // Whole seconds, see Countersign
+	Signature string    `json:"signature"` // Ed25519 signature, hex
 }
 
 // Enums
@@ -70,6 +114,9 @@ const (
 	IDTypeNRIS   IDType = "NRIS"   // National ID
 	IDTypeMSISDN IDType = "MSISDN" // Phone Number
 	IDTypeIBAN   IDType = "IBAN"   // Bank Account
+	// IDTypeALSToken is a blinded "TOKEN:..." destination from an MW-ALS
+	// private alias; only the provider that owns the endpoint can redeem it.
+	IDTypeALSToken IDType = "ALS_TOKEN"
 )
 
 type TxType string
@@ -80,6 +127,15 @@ const (
 	TxTypeB2C TxType = "B2C" // Business to Customer
 )
 
+type Role string
+
+const (
+	RolePayer    Role = "PAYER"
+	RoleGateway  Role = "GATEWAY"
+	RoleProvider Role = "PROVIDER"
+	RoleSwitch   Role = "SWITCH" // National switch
+)
+
 // Helper for strict JSON marshaling if needed
 func (t *Transaction) ToJSON() ([]byte, error) {
 	return json.Marshal(t)
@@ -90,3 +146,49 @@ func FromJSON(data []byte) (*Transaction, error) {
 	err := json.Unmarshal(data, &t)
 	return &t, err
 }
+
+// MarshalJSON writes the header and payload as they were received while
+// they still hold the same values, so a hop that forwards a transaction
+// keeps the sender's signature valid for every verifier downstream.
+func (t Transaction) MarshalJSON() ([]byte, error) {
+	header, payload, err := t.wireParts()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(transactionJSON{
+		MWVersion:  t.MWVersion,
+		Header:     header,
+		Payload:    payload,
+		TrustLayer: trustLayerJSON{TrustLayer: t.TrustLayer, KeyID: t.signedKeyID()},
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler and keeps the received header
+// and payload bytes for signature checks.
+func (t *Transaction) UnmarshalJSON(data []byte) error {
+	var w transactionJSON
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	parsed := Transaction{MWVersion: w.MWVersion, TrustLayer: w.TrustLayer.TrustLayer}
+	if len(w.Header) > 0 {
+		if err := json.Unmarshal(w.Header, &parsed.Header); err != nil {
+			return err
+		}
+	}
+	if len(w.Payload) > 0 {
+		if err := json.Unmarshal(w.Payload, &parsed.Payload); err != nil {
+			return err
+		}
+	}
+	if w.TrustLayer.KeyID != nil {
+		parsed.TrustLayer.KeyID = *w.TrustLayer.KeyID
+	}
+	parsed.received = &receivedJSON{
+		header:       w.Header,
+		payload:      w.Payload,
+		keyIDPresent: w.TrustLayer.KeyID != nil,
+	}
+	*t = parsed
+	return nil
+}
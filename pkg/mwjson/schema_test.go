@@ -1,11 +1,21 @@
 package mwjson_test
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwals"
 	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
 )
 
@@ -20,7 +30,7 @@ func TestTransactionValidation(t *testing.T) {
 			IdempotencyKey: "unique-key-123",
 		},
 		Payload: mwjson.Payload{
-			Amount:   15000.00,
+			Amount:   mwjson.Kwacha(15000),
 			Currency: mwjson.CurrencyMWK,
 			Type:     mwjson.TxTypeP2P,
 			Sender: mwjson.Participant{
@@ -46,12 +56,15 @@ func TestTransactionValidation(t *testing.T) {
 		t.Errorf("Expected valid transaction, got error: %v", err)
 	}
 
-	// Test 2: Invalid Amount Precision
-	tx.Payload.Amount = 15000.123
-	if err := tx.Validate(); err == nil {
+	// Test 2: Invalid Amount Precision (cannot be represented, so rejected on parse)
+	if _, err := mwjson.FromJSON([]byte(`{"payload":{"amount":15000.123}}`)); err == nil {
 		t.Error("Expected error for invalid amount precision, got nil")
 	}
-	tx.Payload.Amount = 15000.00 // Reset
+	tx.Payload.Amount = mwjson.Money{}
+	if err := tx.Validate(); err == nil {
+		t.Error("Expected error for zero amount, got nil")
+	}
+	tx.Payload.Amount = mwjson.Kwacha(15000) // Reset
 
 	// Test 3: Invalid MSISDN
 	tx.Payload.Sender.ID = "123"
@@ -84,7 +97,7 @@ func TestSigning(t *testing.T) {
 			IdempotencyKey: "sig-key-123",
 		},
 		Payload: mwjson.Payload{
-			Amount:   5000.00,
+			Amount:   mwjson.Kwacha(5000),
 			Currency: mwjson.CurrencyMWK,
 			Type:     mwjson.TxTypeP2P,
 			Sender: mwjson.Participant{
@@ -100,7 +113,13 @@ func TestSigning(t *testing.T) {
 		},
 	}
 
-	// Sign
+	// Sign, which needs a UTC timestamp
+	utc := tx.Header.Timestamp
+	tx.Header.Timestamp = utc.In(time.FixedZone("CAT", 2*60*60))
+	if err := tx.SignTransaction(privKey); err == nil {
+		t.Error("Expected error signing a non-UTC timestamp, got nil")
+	}
+	tx.Header.Timestamp = utc
 	if err := tx.SignTransaction(privKey); err != nil {
 		t.Fatalf("Failed to sign transaction: %v", err)
 	}
@@ -114,9 +133,660 @@ func TestSigning(t *testing.T) {
 		t.Errorf("Signature verification failed: %v", err)
 	}
 
-	// Tamper
-	tx.Payload.Amount = 6000.00
+	if tx.TrustLayer.Scheme != mwjson.SchemeJCSEd25519 {
+		t.Errorf("Scheme = %q, want %q", tx.TrustLayer.Scheme, mwjson.SchemeJCSEd25519)
+	}
+
+	// Tamper with fields the legacy pipe format never covered
+	tampers := map[string]func(*mwjson.Transaction){
+		"amount":   func(tx *mwjson.Transaction) { tx.Payload.Amount = mwjson.Kwacha(6000) },
+		"currency": func(tx *mwjson.Transaction) { tx.Payload.Currency = "ZMW" },
+		"provider": func(tx *mwjson.Transaction) { tx.Payload.Receiver.Provider = mwjson.ProviderAirtelMoney },
+		"ttl":      func(tx *mwjson.Transaction) { tx.Header.TTL = 3600 },
+		"scheme":   func(tx *mwjson.Transaction) { tx.TrustLayer.Scheme = mwjson.SchemeLegacyPipe },
+	}
+	for name, tamper := range tampers {
+		tampered := *tx
+		tamper(&tampered)
+		if err := tampered.VerifySignature(pubKey); err == nil {
+			t.Errorf("Expected verification failure after tampering with %s, got nil", name)
+		}
+	}
+
+	// Survives a JSON round trip
+	data, err := tx.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	decoded, err := mwjson.FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+	if err := decoded.VerifySignature(pubKey); err != nil {
+		t.Errorf("Signature verification after round trip failed: %v", err)
+	}
+}
+
+// TestForeignSigner checks signatures made by a non-Go implementation that
+// runs JCS over its own JSON: JavaScript's toISOString always writes
+// milliseconds, and optional members may be sent empty.
+func TestForeignSigner(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for _, ts := range []string{
+		now.Add(120 * time.Millisecond).Format("2006-01-02T15:04:05.000Z"),
+		now.Format("2006-01-02T15:04:05.000Z"),
+		now.Format("2006-01-02T15:04:05+00:00"),
+	} {
+		body := `{
+  "mw_version": "1.0",
+  "header": {"msg_id": "TXN-JS-001", "timestamp": "` + ts + `", "ttl": 300, "idempotency_key": "js-001"},
+  "payload": {
+    "amount": 2500.50, "currency": "MWK", "type": "P2P",
+    "sender": {"id": "265991234567", "id_type": "MSISDN", "provider": "AIRTEL_MONEY", "alias": ""},
+    "receiver": {"id": "265881234567", "id_type": "MSISDN", "provider": "TNM_MPAMBA", "alias": ""},
+    "reference": ""
+  },
+  "trust_layer": {"integrity_hash": "", "kyc_verified": false, "scheme": "mw-jcs-ed25519-v1", "key_id": "", "extension_signature": "SIG"}
+}`
+		// Sign the way the foreign implementation does, from its own JSON
+		var parts map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(body), &parts); err != nil {
+			t.Fatalf("%s: bad test body: %v", ts, err)
+		}
+		msg, err := mwjson.CanonicalJSON(map[string]any{
+			"mw_version": "1.0",
+			"header":     parts["header"],
+			"payload":    parts["payload"],
+			"scheme":     mwjson.SchemeJCSEd25519,
+			"key_id":     "",
+		})
+		if err != nil {
+			t.Fatalf("%s: CanonicalJSON failed: %v", ts, err)
+		}
+		sig := hex.EncodeToString(ed25519.Sign(priv, msg))
+		signed := strings.Replace(body, "SIG", sig, 1)
+
+		tx, err := mwjson.FromJSON([]byte(signed))
+		if err != nil {
+			t.Fatalf("%s: FromJSON failed: %v", ts, err)
+		}
+		if err := tx.VerifySignature(pub); err != nil {
+			t.Errorf("%s: VerifySignature failed: %v", ts, err)
+		}
+		if err := tx.Validate(); err != nil {
+			t.Errorf("%s: Validate failed: %v", ts, err)
+		}
+
+		// Forwarding keeps the received bytes
+		out, err := tx.ToJSON()
+		if err != nil {
+			t.Fatalf("%s: ToJSON failed: %v", ts, err)
+		}
+		forwarded, err := mwjson.FromJSON(out)
+		if err != nil {
+			t.Fatalf("%s: FromJSON failed: %v", ts, err)
+		}
+		if err := forwarded.VerifySignature(pub); err != nil {
+			t.Errorf("%s: VerifySignature after forwarding failed: %v", ts, err)
+		}
+
+		// Editing the parsed values still breaks the signature
+		tx.Payload.Amount = mwjson.Kwacha(25000)
+		if err := tx.VerifySignature(pub); err == nil {
+			t.Errorf("%s: expected error after changing the amount, got nil", ts)
+		}
+	}
+}
+
+func TestIntegrityHash(t *testing.T) {
+	tx := &mwjson.Transaction{
+		MWVersion: mwjson.MWJSONVersion,
+		Header: mwjson.Header{
+			MsgID:          "TXN-HASH-001",
+			Timestamp:      time.Now().UTC(),
+			TTL:            300,
+			IdempotencyKey: "hash-key-123",
+		},
+		Payload: mwjson.Payload{
+			Amount:   mwjson.Kwacha(2500),
+			Currency: mwjson.CurrencyMWK,
+			Type:     mwjson.TxTypeP2P,
+			Sender:   mwjson.Participant{ID: "265991234567", IDType: mwjson.IDTypeMSISDN, Provider: mwjson.ProviderAirtelMoney},
+			Receiver: mwjson.Participant{ID: "265881234567", IDType: mwjson.IDTypeMSISDN, Provider: mwjson.ProviderTNMPamba},
+		},
+	}
+
+	if err := tx.VerifyIntegrity(); err == nil {
+		t.Error("Expected error for missing integrity hash, got nil")
+	}
+
+	_, privKey, _ := ed25519.GenerateKey(rand.Reader)
+	if err := tx.SignTransaction(privKey); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+	fp, err := tx.Fingerprint()
+	if err != nil {
+		t.Fatalf("Fingerprint failed: %v", err)
+	}
+	if len(fp) != 64 || tx.TrustLayer.IntegrityHash != fp {
+		t.Errorf("IntegrityHash = %q, want fingerprint %q", tx.TrustLayer.IntegrityHash, fp)
+	}
+	if err := tx.VerifyIntegrity(); err != nil {
+		t.Errorf("VerifyIntegrity failed: %v", err)
+	}
+
+	// The fingerprint ignores the trust layer
+	tx.TrustLayer.KYCVerified = true
+	if again, _ := tx.Fingerprint(); again != fp {
+		t.Error("Fingerprint changed with the trust layer")
+	}
+
+	// Corruption in transit fails Validate without any key
+	tx.Payload.Receiver.ID = "265881234568"
+	if err := tx.Validate(); err == nil {
+		t.Error("Expected Validate to fail on integrity hash mismatch, got nil")
+	}
+}
+
+func TestKeyResolver(t *testing.T) {
+	pubKey, privKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	alsKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	registryPub, registryKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	als, _ := mwals.NewService(registryKey, "")
+	als.Seed(&mwals.AliasRecord{Alias: "john", Status: mwals.AliasStatusActive, PublicKey: hex.EncodeToString(pubKey)})
+	als.Seed(&mwals.AliasRecord{Alias: "mallory", Status: mwals.AliasStatusActive, PublicKey: hex.EncodeToString(otherKey.Public().(ed25519.PublicKey))})
+	als.Seed(&mwals.AliasRecord{Alias: "dormant", Status: mwals.AliasStatusPending, PublicKey: hex.EncodeToString(alsKey)})
+	resolver := mwjson.NewALSKeyResolver(als, registryPub)
+	ctx := context.Background()
+
+	if got := mwjson.ALSKeyID(" @John"); got != "als:@john" {
+		t.Errorf("ALSKeyID = %q, want als:@john", got)
+	}
+	if _, err := resolver.ResolveKey(ctx, "als:@dormant"); err == nil {
+		t.Error("Expected error for inactive alias, got nil")
+	}
+	if _, err := resolver.ResolveKey(ctx, "hsm:42"); err == nil {
+		t.Error("Expected error for non-ALS key ID, got nil")
+	}
+	if _, err := resolver.ResolveKey(ctx, "als:@john"); err != nil {
+		t.Errorf("ResolveKey failed: %v", err)
+	}
+
+	// Responses the registry did not sign are not trusted
+	unsignedALS, _ := mwals.NewService(nil, "")
+	unsignedALS.Seed(&mwals.AliasRecord{Alias: "john", Status: mwals.AliasStatusActive, PublicKey: hex.EncodeToString(pubKey)})
+	if _, err := mwjson.NewALSKeyResolver(unsignedALS, registryPub).ResolveKey(ctx, "als:@john"); err == nil {
+		t.Error("Expected error for unsigned registry response, got nil")
+	}
+	_, impostorKey, _ := ed25519.GenerateKey(rand.Reader)
+	impostorALS, _ := mwals.NewService(impostorKey, "")
+	impostorALS.Seed(&mwals.AliasRecord{Alias: "john", Status: mwals.AliasStatusActive, PublicKey: hex.EncodeToString(otherKey.Public().(ed25519.PublicKey))})
+	if _, err := mwjson.NewALSKeyResolver(impostorALS, registryPub).ResolveKey(ctx, "als:@john"); err == nil {
+		t.Error("Expected error for response signed by another registry, got nil")
+	}
+	swapped, _ := als.Resolve(ctx, "john")
+	swapped.PublicKey = hex.EncodeToString(otherKey.Public().(ed25519.PublicKey))
+	if err := mwals.VerifyResolution(swapped, registryPub); err == nil {
+		t.Error("Expected error for public key swapped in transit, got nil")
+	}
+
+	newTx := func() *mwjson.Transaction {
+		return &mwjson.Transaction{
+			MWVersion: mwjson.MWJSONVersion,
+			Header:    mwjson.Header{MsgID: "TXN-KEY-001", Timestamp: time.Now().UTC(), TTL: 300, IdempotencyKey: "key-123"},
+			Payload: mwjson.Payload{
+				Amount:   mwjson.Kwacha(1000),
+				Currency: mwjson.CurrencyMWK,
+				Type:     mwjson.TxTypeP2P,
+				Sender:   mwjson.Participant{ID: "265991234567", Alias: "@john"},
+				Receiver: mwjson.Participant{ID: "265881234567"},
+			},
+		}
+	}
+
+	// Explicit key ID
+	tx := newTx()
+	tx.TrustLayer.KeyID = mwjson.ALSKeyID(tx.Payload.Sender.Alias)
+	if err := tx.SignTransaction(privKey); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+	if err := tx.VerifyWithResolver(ctx, resolver); err != nil {
+		t.Errorf("VerifyWithResolver failed: %v", err)
+	}
+
+	// The key ID is signed
+	tx.TrustLayer.KeyID = "hsm:42"
 	if err := tx.VerifySignature(pubKey); err == nil {
-		t.Error("Expected verification failure after tampering, got nil")
+		t.Error("Expected error for key ID changed after signing, got nil")
+	}
+	if err := tx.VerifyWithResolver(ctx, resolver); err == nil {
+		t.Error("Expected error for non-ALS sender key ID, got nil")
+	}
+
+	// Falls back to the sender's alias
+	tx = newTx()
+	if err := tx.SignTransaction(privKey); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+	if err := tx.VerifyWithResolver(ctx, resolver); err != nil {
+		t.Errorf("VerifyWithResolver without key ID failed: %v", err)
+	}
+
+	// Signing for @john with @mallory's key
+	forged := newTx()
+	forged.TrustLayer.KeyID = "als:@mallory"
+	if err := forged.SignTransaction(otherKey); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+	if err := forged.VerifyWithResolver(ctx, resolver); err == nil {
+		t.Error("Expected error for key ID that does not match sender, got nil")
+	}
+	forged.TrustLayer.KeyID = ""
+	if err := forged.VerifyWithResolver(ctx, resolver); err == nil {
+		t.Error("Expected error for signature by another alias's key, got nil")
+	}
+}
+
+func TestCountersignatureChain(t *testing.T) {
+	payerPub, payerKey, _ := ed25519.GenerateKey(rand.Reader)
+	gatewayPub, gatewayKey, _ := ed25519.GenerateKey(rand.Reader)
+	providerPub, providerKey, _ := ed25519.GenerateKey(rand.Reader)
+	registryPub, registryKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	als, _ := mwals.NewService(registryKey, "")
+	for alias, key := range map[string]ed25519.PublicKey{"john": payerPub, "paygate": gatewayPub, "airtel_mw": providerPub} {
+		als.Seed(&mwals.AliasRecord{Alias: alias, Status: mwals.AliasStatusActive, PublicKey: hex.EncodeToString(key)})
+	}
+	resolver := mwjson.NewALSKeyResolver(als, registryPub)
+	ctx := context.Background()
+
+	tx := &mwjson.Transaction{
+		MWVersion: mwjson.MWJSONVersion,
+		Header:    mwjson.Header{MsgID: "TXN-CHAIN-001", Timestamp: time.Now().UTC(), TTL: 300, IdempotencyKey: "chain-123"},
+		Payload: mwjson.Payload{
+			Amount:   mwjson.Kwacha(7500),
+			Currency: mwjson.CurrencyMWK,
+			Type:     mwjson.TxTypeC2B,
+			Sender:   mwjson.Participant{ID: "265991234567", Alias: "@john"},
+			Receiver: mwjson.Participant{ID: "265881234567"},
+		},
+	}
+
+	if err := tx.Countersign(mwjson.RoleGateway, "als:@paygate", gatewayKey); err == nil {
+		t.Error("Expected error countersigning an unsigned transaction, got nil")
+	}
+	if err := tx.SignTransaction(payerKey); err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+	if err := tx.Countersign("BYSTANDER", "als:@paygate", gatewayKey); err == nil {
+		t.Error("Expected error for unknown role, got nil")
+	}
+	if err := tx.Countersign(mwjson.RoleGateway, "als:@paygate", gatewayKey); err != nil {
+		t.Fatalf("Gateway countersign failed: %v", err)
+	}
+	if err := tx.Countersign(mwjson.RoleProvider, "als:@airtel_mw", providerKey); err != nil {
+		t.Fatalf("Provider countersign failed: %v", err)
+	}
+
+	if err := tx.VerifyChain(ctx, resolver); err != nil {
+		t.Fatalf("VerifyChain failed: %v", err)
+	}
+	if err := tx.VerifyCountersignature(0, gatewayPub); err != nil {
+		t.Errorf("VerifyCountersignature(0) failed: %v", err)
+	}
+
+	data, err := tx.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	decoded, err := mwjson.FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+	if err := decoded.VerifyChain(ctx, resolver); err != nil {
+		t.Errorf("VerifyChain after round trip failed: %v", err)
+	}
+
+	// The protobuf encoding keeps Unix seconds only
+	for i, c := range decoded.TrustLayer.Countersignatures {
+		if c.Timestamp.Nanosecond() != 0 {
+			t.Errorf("countersignature %d timestamp %v has sub-second precision", i, c.Timestamp)
+		}
+	}
+	viaProto := *decoded
+	viaProto.TrustLayer.Countersignatures = nil
+	for _, c := range decoded.TrustLayer.Countersignatures {
+		c.Timestamp = time.Unix(c.Timestamp.Unix(), 0)
+		viaProto.TrustLayer.Countersignatures = append(viaProto.TrustLayer.Countersignatures, c)
+	}
+	if err := viaProto.VerifyChain(ctx, resolver); err != nil {
+		t.Errorf("VerifyChain after seconds round trip failed: %v", err)
+	}
+
+	// Dropping the gateway breaks the provider's countersignature
+	dropped := *decoded
+	dropped.TrustLayer.Countersignatures = decoded.TrustLayer.Countersignatures[1:]
+	if err := dropped.VerifyChain(ctx, resolver); err == nil {
+		t.Error("Expected error after removing a countersignature, got nil")
+	}
+
+	// Changing an earlier hop's timestamp breaks both hops
+	decoded.TrustLayer.Countersignatures[0].Timestamp = decoded.TrustLayer.Countersignatures[0].Timestamp.Add(-time.Second)
+	if err := decoded.VerifyCountersignature(1, providerPub); err == nil {
+		t.Error("Expected provider countersignature to cover the gateway entry, got nil")
+	}
+
+	// Tampering with the payload breaks every signature
+	tx.Payload.Amount = mwjson.Kwacha(75000)
+	if err := tx.VerifyCountersignature(1, providerPub); err == nil {
+		t.Error("Expected countersignature to cover the payload, got nil")
+	}
+}
+
+func TestLegacySignature(t *testing.T) {
+	pubKey, privKey, _ := ed25519.GenerateKey(rand.Reader)
+	ts := time.Now().UTC()
+	tx := &mwjson.Transaction{
+		MWVersion: mwjson.MWJSONVersion,
+		Header:    mwjson.Header{MsgID: "TXN-LEGACY-001", Timestamp: ts, TTL: 300},
+		Payload: mwjson.Payload{
+			Amount:   mwjson.Kwacha(5000),
+			Sender:   mwjson.Participant{ID: "265991234567"},
+			Receiver: mwjson.Participant{ID: "265881234567"},
+		},
+	}
+	legacy := "TXN-LEGACY-001|" + ts.Format(time.RFC3339) + "|5000.00|265991234567|265881234567"
+	tx.TrustLayer.Signature = hex.EncodeToString(ed25519.Sign(privKey, []byte(legacy)))
+
+	if err := tx.VerifySignature(pubKey); err != nil {
+		t.Errorf("Legacy signature rejected during migration window: %v", err)
+	}
+
+	saved := mwjson.LegacySchemeAcceptedUntil
+	defer func() { mwjson.LegacySchemeAcceptedUntil = saved }()
+	mwjson.LegacySchemeAcceptedUntil = time.Now().Add(-time.Hour)
+	if err := tx.VerifySignature(pubKey); err == nil {
+		t.Error("Expected legacy signature to be rejected after migration window")
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	in := map[string]any{
+		"b":      2,
+		"a":      []any{1.0, "x\u000f\"é", true, nil},
+		"c":      map[string]any{"z": 1e21, "y": -0.000001, "x": 2500.50},
+		"\u20ac": "euro",
+		"\r":     "cr",
+	}
+	got, err := mwjson.CanonicalJSON(in)
+	if err != nil {
+		t.Fatalf("CanonicalJSON failed: %v", err)
+	}
+	want := `{"\r":"cr","a":[1,"x\u000f\"é",true,null],"b":2,"c":{"x":2500.5,"y":-0.000001,"z":1e+21},"€":"euro"}`
+	if string(got) != want {
+		t.Errorf("CanonicalJSON =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		tambala int64
+		str     string
+		json    string
+	}{
+		{"2500", 250000, "2500.00", "2500"},
+		{"2500.5", 250050, "2500.50", "2500.5"},
+		{"2500.05", 250005, "2500.05", "2500.05"},
+		{"0.1", 10, "0.10", "0.1"},
+		{"1.5e3", 150000, "1500.00", "1500"},
+		{"-7.25", -725, "-7.25", "-7.25"},
+	}
+	for _, tt := range tests {
+		m, err := mwjson.ParseMoney(tt.in)
+		if err != nil {
+			t.Fatalf("ParseMoney(%q) failed: %v", tt.in, err)
+		}
+		if m.Tambala() != tt.tambala || m.String() != tt.str {
+			t.Errorf("ParseMoney(%q) = %d (%s), want %d (%s)", tt.in, m.Tambala(), m, tt.tambala, tt.str)
+		}
+		out, _ := json.Marshal(m)
+		if string(out) != tt.json {
+			t.Errorf("json.Marshal(%s) = %s, want %s", m, out, tt.json)
+		}
+	}
+
+	for _, bad := range []string{"", "abc", "1/3", "0.001", "15000.123", "1e30"} {
+		if _, err := mwjson.ParseMoney(bad); err == nil {
+			t.Errorf("ParseMoney(%q): expected error, got nil", bad)
+		}
+	}
+
+	// Arithmetic is exact: 0.10 + 0.20 is 0.30.
+	sum := mwjson.Tambala(10).Add(mwjson.Tambala(20))
+	if sum != mwjson.Tambala(30) || sum.Cmp(mwjson.Tambala(30)) != 0 {
+		t.Errorf("0.10 + 0.20 = %s", sum)
+	}
+	if got, err := mwjson.Kwacha(2500).Percent(250); err != nil || got != mwjson.Tambala(6250) {
+		t.Errorf("2.5%% of 2500 = %s, %v; want 62.50", got, err)
+	}
+	if got, err := mwjson.Kwacha(15).Percent(230); err != nil || got != mwjson.Tambala(35) {
+		t.Errorf("2.3%% of 15.00 = %s, %v; want 0.35", got, err)
+	}
+	for _, tt := range []struct {
+		amount mwjson.Money
+		rate   *big.Rat
+		want   mwjson.Money
+	}{
+		{mwjson.Kwacha(5000), big.NewRat(155, 10000), mwjson.Tambala(7750)},
+		{mwjson.Tambala(7750), big.NewRat(10000, 155), mwjson.Kwacha(5000)},
+		{mwjson.Tambala(1), big.NewRat(1, 2), mwjson.Tambala(1)},   // Half rounds away from zero
+		{mwjson.Tambala(-1), big.NewRat(1, 2), mwjson.Tambala(-1)}, // ...both ways
+		{mwjson.Tambala(10), big.NewRat(1, 3), mwjson.Tambala(3)},
+	} {
+		if got, err := tt.amount.MulRat(tt.rate); err != nil || got != tt.want {
+			t.Errorf("%s x %s = %s, %v; want %s", tt.amount, tt.rate.RatString(), got, err, tt.want)
+		}
+	}
+	if _, err := mwjson.Tambala(math.MaxInt64).MulRat(big.NewRat(2, 1)); err == nil {
+		t.Error("Expected overflow error, got nil")
+	}
+
+	// Wire format round trip
+	var p mwjson.Payload
+	if err := json.Unmarshal([]byte(`{"amount":2500.10}`), &p); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if p.Amount != mwjson.Tambala(250010) {
+		t.Errorf("Unmarshal amount = %s, want 2500.10", p.Amount)
+	}
+	if err := json.Unmarshal([]byte(`{"amount":"2500"}`), &p); err == nil {
+		t.Error("Expected error for string amount, got nil")
+	}
+}
+
+// countingProvider is a PaymentProvider that counts calls and can be made
+// to fail in the ways real providers do.
+type countingProvider struct {
+	calls   int
+	reject  bool              // Refuse with insufficient funds
+	down    bool              // Unreachable, nothing charged
+	lost    bool              // Charge, then drop the response
+	charged map[string]string // msg_id to transaction ID
+}
+
+func (p *countingProvider) Authorize(ctx context.Context, tx *mwjson.Transaction) (string, error) {
+	return p.Transfer(ctx, tx)
+}
+
+func (p *countingProvider) Transfer(ctx context.Context, tx *mwjson.Transaction) (string, error) {
+	p.calls++
+	switch {
+	case p.reject:
+		return "", mwjson.NewMWError(mwjson.ErrInsufficientFunds, "Insufficient Funds", "")
+	case p.down:
+		return "", mwjson.NewMWError(mwjson.ErrProviderDown, "Provider Down", "")
+	}
+	id := fmt.Sprintf("AM-%d", p.calls)
+	if p.charged == nil {
+		p.charged = make(map[string]string)
+	}
+	p.charged[tx.Header.MsgID] = id
+	if p.lost {
+		return "", errors.New("read tcp: connection reset by peer")
+	}
+	return id, nil
+}
+
+func (p *countingProvider) QueryStatus(ctx context.Context, msgID string) (*mwjson.TransactionStatus, error) {
+	if id, ok := p.charged[msgID]; ok {
+		return &mwjson.TransactionStatus{MsgID: msgID, Status: "SUCCESS", TransactionID: id}, nil
+	}
+	return &mwjson.TransactionStatus{MsgID: msgID, Status: "PENDING"}, nil
+}
+
+func TestIdempotentProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idempotency.json")
+	fileStore, err := mwjson.NewFileIdempotencyStore(path)
+	if err != nil {
+		t.Fatalf("NewFileIdempotencyStore failed: %v", err)
+	}
+	stores := map[string]mwjson.IdempotencyStore{
+		"memory": mwjson.NewMemoryIdempotencyStore(),
+		"file":   fileStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			backend := &countingProvider{}
+			provider := mwjson.NewIdempotentProvider(backend, store)
+			newTx := func(amount int64) *mwjson.Transaction {
+				return &mwjson.Transaction{
+					MWVersion: mwjson.MWJSONVersion,
+					Header:    mwjson.Header{MsgID: "TXN-IDEM-" + name, Timestamp: time.Now().UTC(), TTL: 300, IdempotencyKey: "idem-" + name},
+					Payload: mwjson.Payload{
+						Amount:   mwjson.Kwacha(amount),
+						Currency: mwjson.CurrencyMWK,
+						Type:     mwjson.TxTypeP2P,
+						Sender:   mwjson.Participant{ID: "265991234567"},
+						Receiver: mwjson.Participant{ID: "265881234567"},
+					},
+				}
+			}
+
+			first, err := provider.Transfer(ctx, newTx(2500))
+			if err != nil {
+				t.Fatalf("Transfer failed: %v", err)
+			}
+
+			// A retry with a fresh timestamp replays the original result
+			retry, err := provider.Transfer(ctx, newTx(2500))
+			if err != nil || retry != first {
+				t.Errorf("Retry = %q, %v; want %q", retry, err, first)
+			}
+			if backend.calls != 1 {
+				t.Errorf("Provider called %d times, want 1", backend.calls)
+			}
+
+			// Same key, different payload
+			_, err = provider.Transfer(ctx, newTx(25000))
+			var mwErr *mwjson.MWError
+			if !errors.As(err, &mwErr) || mwErr.Code != mwjson.ErrDuplicateTx {
+				t.Errorf("Expected %s for reused key, got %v", mwjson.ErrDuplicateTx, err)
+			}
+
+			// Authorize is tracked separately from Transfer
+			if _, err := provider.Authorize(ctx, newTx(2500)); err != nil || backend.calls != 2 {
+				t.Errorf("Authorize = %v after %d calls, want a fresh call", err, backend.calls)
+			}
+
+			// Rejections are released so the client can retry
+			failing := newTx(100)
+			failing.Header.IdempotencyKey = "idem-fail-" + name
+			backend.reject = true
+			if _, err := provider.Transfer(ctx, failing); err == nil {
+				t.Fatal("Expected provider error, got nil")
+			}
+			backend.reject = false
+			if _, err := provider.Transfer(ctx, failing); err != nil {
+				t.Errorf("Retry after rejection failed: %v", err)
+			}
+
+			// The provider charges but the response is lost: the retry
+			// learns the outcome from QueryStatus instead of charging again
+			lostTx := newTx(4000)
+			lostTx.Header.MsgID = "TXN-LOST-" + name
+			lostTx.Header.IdempotencyKey = "idem-lost-" + name
+			backend.lost = true
+			if _, err := provider.Transfer(ctx, lostTx); err == nil {
+				t.Fatal("Expected transport error, got nil")
+			}
+			backend.lost = false
+			calls := backend.calls
+			charged := backend.charged[lostTx.Header.MsgID]
+			retryTx := newTx(4000)
+			retryTx.Header.IdempotencyKey = lostTx.Header.IdempotencyKey
+			if got, err := provider.Transfer(ctx, retryTx); err != nil || got != charged {
+				t.Errorf("Retry after lost response = %q, %v; want %q", got, err, charged)
+			}
+			if backend.calls != calls {
+				t.Errorf("Provider called %d times after lost response, want %d", backend.calls, calls)
+			}
+
+			// An outage with no outcome keeps the key reserved
+			downTx := newTx(300)
+			downTx.Header.MsgID = "TXN-DOWN-" + name
+			downTx.Header.IdempotencyKey = "idem-down-" + name
+			backend.down = true
+			if _, err := provider.Transfer(ctx, downTx); err == nil {
+				t.Fatal("Expected provider error, got nil")
+			}
+			backend.down = false
+			calls = backend.calls
+			if _, err := provider.Transfer(ctx, downTx); !errors.As(err, &mwErr) || mwErr.Code != mwjson.ErrDuplicateTx {
+				t.Errorf("Expected %s while outcome is unknown, got %v", mwjson.ErrDuplicateTx, err)
+			}
+			if backend.calls != calls {
+				t.Error("Provider called again while outcome is unknown")
+			}
+
+			// Expired transactions never reach the provider
+			expired := newTx(500)
+			expired.Header.IdempotencyKey = "idem-expired-" + name
+			expired.Header.Timestamp = time.Now().UTC().Add(-10 * time.Minute)
+			if _, err := provider.Transfer(ctx, expired); !errors.As(err, &mwErr) || mwErr.Code != mwjson.ErrGhostTransaction {
+				t.Errorf("Expected %s for expired transaction, got %v", mwjson.ErrGhostTransaction, err)
+			}
+			if backend.calls != calls {
+				t.Error("Provider called for expired transaction")
+			}
+
+			// Records outlive a short TTL, timed from when the gateway saw them
+			existing, err := store.Reserve(ctx, mwjson.IdempotencyRecord{Key: "transfer/265991234567/idem-" + name})
+			if err != nil || existing == nil {
+				t.Fatalf("Record missing: %v", err)
+			}
+			if time.Until(existing.ExpiresAt) < mwjson.MinIdempotencyTTL-time.Minute {
+				t.Errorf("Record expires at %v, want at least %v from now", existing.ExpiresAt, mwjson.MinIdempotencyTTL)
+			}
+		})
+	}
+
+	// The file store survives a restart
+	reopened, err := mwjson.NewFileIdempotencyStore(path)
+	if err != nil {
+		t.Fatalf("Reopening file store failed: %v", err)
+	}
+	existing, err := reopened.Reserve(context.Background(), mwjson.IdempotencyRecord{
+		Key:       "transfer/265991234567/idem-file",
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	if err != nil || existing == nil || !existing.Completed || existing.Result != "AM-1" {
+		t.Errorf("Reopened store lost the record: %+v, %v", existing, err)
 	}
 }
@@ -1,63 +1,207 @@
 package mwjson
 
 import (
+	"bytes"
 	"crypto/ed25519"
+	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
-// SignTransaction generates a signature for the transaction using the sender's private key.
-// It populates the TrustLayer.Signature field.
+// Signature schemes carried in TrustLayer.Scheme.
+const (
+	// SchemeJCSEd25519 signs the RFC 8785 canonical form of mw_version,
+	// header, payload and the scheme name itself.
+	SchemeJCSEd25519 = "mw-jcs-ed25519-v1"
+	// SchemeLegacyPipe signs "msg_id|timestamp|amount|sender|receiver".
+	// Transactions without a scheme are treated as legacy.
+	SchemeLegacyPipe = "mw-pipe-ed25519-v0"
+)
+
+// LegacySchemeAcceptedUntil ends the migration window: after it,
+// VerifySignature rejects SchemeLegacyPipe signatures.
+var LegacySchemeAcceptedUntil = time.Date(2027, time.March, 31, 23, 59, 59, 0, time.UTC)
+
+// signedDocument is the part of a transaction covered by SchemeJCSEd25519
+// and, without a scheme, by the integrity hash.
+type signedDocument struct {
+	MWVersion string          `json:"mw_version"`
+	Header    json.RawMessage `json:"header"`
+	Payload   json.RawMessage `json:"payload"`
+	Scheme    string          `json:"scheme,omitempty"`
+	KeyID     *string         `json:"key_id,omitempty"`
+}
+
+// canonicalDocument returns the JCS form of mw_version, header and payload.
+// When a scheme is given it also covers the scheme name and
+// TrustLayer.KeyID, so the key a signature claims cannot be swapped
+// afterwards (like the JWS "kid" header). The header and payload are the
+// JSON as received (see wireParts), so any JCS implementation run over the
+// received JSON gets the same bytes.
+func (t *Transaction) canonicalDocument(scheme string) ([]byte, error) {
+	header, payload, err := t.wireParts()
+	if err != nil {
+		return nil, err
+	}
+	doc := signedDocument{
+		MWVersion: t.MWVersion,
+		Header:    header,
+		Payload:   payload,
+		Scheme:    scheme,
+	}
+	if scheme != "" {
+		doc.KeyID = t.signedKeyID()
+	}
+	return CanonicalJSON(doc)
+}
+
+// wireParts returns the header and payload as they were received, or as
+// Go marshals them when the transaction was built locally or has been
+// changed since. Received bytes are only used while they decode to the
+// current values, so editing a parsed transaction still breaks its
+// signature.
+func (t *Transaction) wireParts() (header, payload json.RawMessage, err error) {
+	if header, err = json.Marshal(t.Header); err != nil {
+		return nil, nil, err
+	}
+	if payload, err = json.Marshal(t.Payload); err != nil {
+		return nil, nil, err
+	}
+	if t.received == nil {
+		return header, payload, nil
+	}
+	if sameValue(t.received.header, header, new(Header)) {
+		header = t.received.header
+	}
+	if sameValue(t.received.payload, payload, new(Payload)) {
+		payload = t.received.payload
+	}
+	return header, payload, nil
+}
+
+// sameValue reports whether raw decodes into v with the same Go marshaling
+// as current.
+func sameValue(raw, current json.RawMessage, v any) bool {
+	if len(raw) == 0 || json.Unmarshal(raw, v) != nil {
+		return false
+	}
+	again, err := json.Marshal(v)
+	return err == nil && bytes.Equal(again, current)
+}
+
+// signedKeyID returns the key_id member as it appears on the wire: absent
+// when empty, unless an empty key_id was received.
+func (t *Transaction) signedKeyID() *string {
+	if t.TrustLayer.KeyID == "" && (t.received == nil || !t.received.keyIDPresent) {
+		return nil
+	}
+	keyID := t.TrustLayer.KeyID
+	return &keyID
+}
+
+// Fingerprint returns the hex SHA-256 digest of the canonical header and
+// payload. It does not depend on the trust layer, so it identifies the
+// transaction in logs before and after signing.
+func (t *Transaction) Fingerprint() (string, error) {
+	doc, err := t.canonicalDocument("")
+	if err != nil {
+		return "", NewMWError(ErrInternalError, "Canonicalization Failed", err.Error())
+	}
+	sum := sha256.Sum256(doc)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// SetIntegrityHash stores the transaction's Fingerprint in TrustLayer.IntegrityHash.
+func (t *Transaction) SetIntegrityHash() error {
+	fp, err := t.Fingerprint()
+	if err != nil {
+		return err
+	}
+	t.TrustLayer.IntegrityHash = fp
+	return nil
+}
+
+// VerifyIntegrity checks TrustLayer.IntegrityHash against the header and
+// payload. It needs no key, so intermediaries can use it to detect
+// corruption in transit; it does not prove who sent the transaction.
+func (t *Transaction) VerifyIntegrity() error {
+	if t.TrustLayer.IntegrityHash == "" {
+		return NewMWError(ErrSchemaValidation, "Missing Integrity Hash", "")
+	}
+	fp, err := t.Fingerprint()
+	if err != nil {
+		return err
+	}
+	if fp != strings.ToLower(t.TrustLayer.IntegrityHash) {
+		return NewMWError(ErrSchemaValidation, "Integrity Hash Mismatch", "Header or payload altered in transit")
+	}
+	return nil
+}
+
+// SigningInput returns the bytes the signature covers under scheme.
+func (t *Transaction) SigningInput(scheme string) ([]byte, error) {
+	switch scheme {
+	case SchemeJCSEd25519:
+		return t.canonicalDocument(scheme)
+	case SchemeLegacyPipe, "":
+		return []byte(fmt.Sprintf("%s|%s|%s|%s|%s",
+			t.Header.MsgID,
+			t.Header.Timestamp.UTC().Format(time.RFC3339),
+			t.Payload.Amount.String(),
+			t.Payload.Sender.ID,
+			t.Payload.Receiver.ID,
+		)), nil
+	default:
+		return nil, NewMWError(ErrInvalidSignature, "Unknown Signature Scheme", scheme)
+	}
+}
+
+// SignTransaction signs the canonical header and payload with the sender's
+// private key using SchemeJCSEd25519, and populates TrustLayer.IntegrityHash,
+// TrustLayer.Scheme and TrustLayer.Signature.
 func (t *Transaction) SignTransaction(privateKey ed25519.PrivateKey) error {
-	
-	// 1. Create the canonical string to sign
-	// We need to sign the immutable parts: Header and Payload.
-	// We exclude TrustLayer itself to avoid recursion, though IntegrityHash is part of it.
-	// A common pattern is to sign the hash of the payload + header.
-
-	// Concatenate Header + Payload
-	// In a real spec, we'd need a very strict canonicalization (e.g., JCS).
-	// For this standard, we'll assume the byte buffer matches what is sent,
-	// OR we sign a constructed string like "msg_id|timestamp|amount|sender|receiver" to be safe against JSON formatting issues.
-	// Let's go with the constructed string approach for robustness in this MVP.
-
-	canonicalString := fmt.Sprintf("%s|%s|%.2f|%s|%s",
-		t.Header.MsgID,
-		t.Header.Timestamp.UTC().Format(time.RFC3339),
-		t.Payload.Amount,
-		t.Payload.Sender.ID,
-		t.Payload.Receiver.ID,
-	)
-
-	// 2. Sign
-	signature := ed25519.Sign(privateKey, []byte(canonicalString))
-
-	// 3. Encode to Hex and set
-	t.TrustLayer.Signature = hex.EncodeToString(signature)
-
-	// Can also set integrity hash (SHA256 of the canonical string or payload) .... good for "TrustLayer"
-	// t.TrustLayer.IntegrityHash = ... (Skipping for now as Signature covers it)
+	// The signed header must match the wire JSON byte for byte, so the
+	// timestamp is never normalized behind the caller's back.
+	if _, offset := t.Header.Timestamp.Zone(); offset != 0 {
+		return NewMWError(ErrSchemaValidation, "Timestamp must be in UTC", "Convert with Timestamp.UTC() before signing")
+	}
+	if err := t.SetIntegrityHash(); err != nil {
+		return err
+	}
 
+	msg, err := t.SigningInput(SchemeJCSEd25519)
+	if err != nil {
+		return NewMWError(ErrInvalidSignature, "Canonicalization Failed", err.Error())
+	}
+
+	t.TrustLayer.Scheme = SchemeJCSEd25519
+	t.TrustLayer.Signature = hex.EncodeToString(ed25519.Sign(privateKey, msg))
 	return nil
 }
 
 // VerifySignature checks if the transaction signature is valid for the given public key.
+// Legacy pipe signatures are accepted until LegacySchemeAcceptedUntil.
 func (t *Transaction) VerifySignature(publicKey ed25519.PublicKey) error {
 	if t.TrustLayer.Signature == "" {
 		return NewMWError(ErrInvalidSignature, "Missing Signature", "")
 	}
 
-	// 1. Reconstruct Canonical String
-	canonicalString := fmt.Sprintf("%s|%s|%.2f|%s|%s",
-		t.Header.MsgID,
-		t.Header.Timestamp.UTC().Format(time.RFC3339),
-		t.Payload.Amount,
-		t.Payload.Sender.ID,
-		t.Payload.Receiver.ID,
-	)
+	scheme := t.TrustLayer.Scheme
+	if scheme == "" || scheme == SchemeLegacyPipe {
+		if time.Now().After(LegacySchemeAcceptedUntil) {
+			return NewMWError(ErrInvalidSignature, "Legacy Signature Scheme Retired",
+				"Re-sign with "+SchemeJCSEd25519)
+		}
+	}
+
+	msg, err := t.SigningInput(scheme)
+	if err != nil {
+		return err
+	}
 
-	// 2. Decode Signature
 	sigBytes, err := hex.DecodeString(t.TrustLayer.Signature)
 	if err != nil {
 		return NewMWError(ErrInvalidSignature, "Invalid Signature Format", "Not Hex")
@@ -67,9 +211,7 @@ func (t *Transaction) VerifySignature(publicKey ed25519.PublicKey) error {
 		return NewMWError(ErrInvalidSignature, "Invalid Signature Length", "")
 	}
 
-	// 3. Verify
-	valid := ed25519.Verify(publicKey, []byte(canonicalString), sigBytes)
-	if !valid {
+	if !ed25519.Verify(publicKey, msg, sigBytes) {
 		return NewMWError(ErrInvalidSignature, "Signature Verification Failed", "")
 	}
 
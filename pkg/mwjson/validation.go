@@ -3,7 +3,6 @@ package mwjson
 import (
 	"errors"
 	"fmt"
-	"math"
 	"regexp"
 	"strings"
 	"time"
@@ -27,7 +26,8 @@ func (t *Transaction) Validate() error {
 		return NewMWError(ErrSchemaValidation, "Missing Timestamp", "")
 	}
 	// Force UTC check (or at least awareness) - The user asked to "Force UTC"
-	if t.Header.Timestamp.Location() != time.UTC {
+	// "Z" and "+00:00" are both UTC
+	if _, offset := t.Header.Timestamp.Zone(); offset != 0 {
 		return NewMWError(ErrSchemaValidation, "Timestamp must be in UTC", "")
 	}
 	// Check/Enforce TTL
@@ -54,21 +54,23 @@ func (t *Transaction) Validate() error {
 		return NewMWError(ErrSchemaValidation, "Invalid Receiver", err.Error())
 	}
 
+	// 5. Integrity (optional until every sender populates it)
+	if t.TrustLayer.IntegrityHash != "" {
+		if err := t.VerifyIntegrity(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// validateAmount ensures the amount is positive and has valid precision for MWK.
-// MWK is typically 2 decimal places, but often used as integer in digital retail.
-func validateAmount(amount float64) error {
-	if amount <= 0 {
+// validateAmount ensures the amount is positive. Precision needs no check:
+// Money cannot hold less than a tambala, and JSON amounts with more than two
+// decimals are rejected when they are parsed.
+func validateAmount(amount Money) error {
+	if !amount.IsPositive() {
 		return NewMWError(ErrSchemaValidation, "Invalid Amount", "Must be greater than 0")
 	}
-	// Check for more than 2 decimal places
-	// Multiply by 100, checking if it's an integer
-	scaled := amount * 100
-	if math.Abs(scaled-math.Round(scaled)) > 0.000001 {
-		return NewMWError(ErrSchemaValidation, "Invalid Amount Precision", "MWK supports up to 2 decimal places")
-	}
 	return nil
 }
 
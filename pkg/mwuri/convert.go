@@ -0,0 +1,124 @@
+package mwuri
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
+	"github.com/frankmwase/malawi-pay-standard/pkg/umqr"
+)
+
+// DraftTTL is the header TTL, in seconds, of transactions drafted from a URI.
+const DraftTTL = 300
+
+// IsAlias reports whether the recipient is an MW-ALS alias rather than an MSISDN.
+func (u *URI) IsAlias() bool {
+	return strings.HasPrefix(u.Recipient, "@")
+}
+
+// FromTransaction builds an unsigned URI that requests the same payment as
+// tx: the receiver's provider and alias (or ID when there is no alias) and
+// the amount.
+func FromTransaction(tx *mwjson.Transaction) (*URI, error) {
+	r := tx.Payload.Receiver
+	recipient := r.Alias
+	if recipient == "" {
+		recipient = r.ID
+	}
+	u := New(string(r.Provider), recipient, tx.Payload.Amount)
+	u.Type = tx.Payload.Type
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Transaction drafts an unsigned MW-JSON transaction of u.Type (P2P when
+// empty) from payer to the URI's recipient. Alias recipients are left with an empty receiver ID for
+// the caller to resolve through MW-ALS; MSISDN recipients are normalized.
+func (u *URI) Transaction(payer mwjson.Participant) (*mwjson.Transaction, error) {
+	receiver := mwjson.Participant{
+		IDType:   mwjson.IDTypeMSISDN,
+		Provider: mwjson.Provider(u.Provider),
+	}
+	if u.IsAlias() {
+		receiver.Alias = u.Recipient
+	} else {
+		id, err := mwjson.NormalizeMSISDN(u.Recipient)
+		if err != nil {
+			return nil, err
+		}
+		receiver.ID = id
+	}
+
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	id := "TXN-" + hex.EncodeToString(b[:])
+	txType := u.Type
+	if txType == "" {
+		txType = mwjson.TxTypeP2P
+	}
+
+	return &mwjson.Transaction{
+		MWVersion: mwjson.MWJSONVersion,
+		Header: mwjson.Header{
+			MsgID:          id,
+			Timestamp:      time.Now().UTC(),
+			TTL:            DraftTTL,
+			IdempotencyKey: id,
+		},
+		Payload: mwjson.Payload{
+			Amount:   u.Amount,
+			Currency: mwjson.CurrencyMWK,
+			Type:     txType,
+			Sender:   payer,
+			Receiver: receiver,
+		},
+	}, nil
+}
+
+// FromUMQR builds an unsigned URI for the merchant account on provider, or
+// for the preferred account (Tag 26) when the QR lists none on that rail.
+// The amount includes any convenience fee. The URI keeps the QR's payment
+// type, so merchant QRs draft C2B transactions.
+func FromUMQR(q *umqr.MerchantQR, provider string) (*URI, error) {
+	if q.Currency != umqr.CurrencyMWK {
+		return nil, fmt.Errorf("%w: currency %s is not MWK", ErrMalformed, q.Currency)
+	}
+	account, ok := q.AccountFor(provider)
+	if !ok {
+		account = q.MerchantAccount
+	}
+
+	var amount mwjson.Money
+	if q.Amount.IsPositive() {
+		var err error
+		if amount, err = q.PayableAmount(mwjson.Money{}); err != nil {
+			return nil, err
+		}
+	}
+
+	u := New(account.AccountType, account.Alias, amount)
+	u.Type = q.TxType()
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// UMQR generates a static merchant UMQR for the URI's recipient. Only alias
+// recipients can be carried, since a UMQR must not expose an MSISDN.
+func (u *URI) UMQR(merchantName, city, mcc string) (string, error) {
+	if err := u.Validate(); err != nil {
+		return "", err
+	}
+	if !u.IsAlias() {
+		return "", fmt.Errorf("%w: UMQR needs an alias recipient, got %q", ErrMalformed, u.Recipient)
+	}
+	return umqr.GenerateMerchantQR(merchantName, city, u.Recipient, u.Provider, mcc, u.Amount, "")
+}
@@ -0,0 +1,194 @@
+package mwuri_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwuri"
+	"github.com/frankmwase/malawi-pay-standard/pkg/umqr"
+)
+
+func TestEncodeParseRoundTrip(t *testing.T) {
+	tests := []struct {
+		uri  *mwuri.URI
+		want string
+	}{
+		{mwuri.New("AIRTEL_MONEY", "0999123456", mwjson.Kwacha(5000)), "mw:1.0:TXN:AIRTEL_MONEY:0999123456:5000"},
+		{mwuri.New("TNM_MPAMBA", "@chifundo", mwjson.Tambala(250050)), "mw:1.0:TXN:TNM_MPAMBA:@chifundo:2500.50"},
+		{mwuri.New("NBM", "@odd:alias 100%", mwjson.Money{}), "mw:1.0:TXN:NBM:@odd%3Aalias%20100%25:"},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.uri.Encode()
+		if err != nil {
+			t.Fatalf("Encode(%+v) failed: %v", tt.uri, err)
+		}
+		if got != tt.want {
+			t.Errorf("Encode = %q, want %q", got, tt.want)
+		}
+		parsed, err := mwuri.Parse(got)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", got, err)
+		}
+		if parsed.Provider != tt.uri.Provider || parsed.Recipient != tt.uri.Recipient || parsed.Amount != tt.uri.Amount {
+			t.Errorf("Parse(%q) = %+v, want %+v", got, parsed, tt.uri)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]error{
+		"umqr:1.0:TXN:AIRTEL_MONEY:0999123456:5000":  mwuri.ErrMalformed,
+		"mw:1.0:TXN:AIRTEL_MONEY:0999123456":         mwuri.ErrMalformed,
+		"mw:1.0:TXN:AIRTEL_MONEY:a:b:5000":           mwuri.ErrMalformed, // Unescaped colon
+		"mw:1.0:TXN:AIRTEL_MONEY:0999123456:50.005":  mwuri.ErrMalformed,
+		"mw:1.0:TXN:AIRTEL_MONEY:0999123456:5000:!!": mwuri.ErrMalformed,
+		"mw:1.0:REFUND:AIRTEL_MONEY:0999123456:5000": mwuri.ErrMalformed,
+		"mw:2.0:TXN:AIRTEL_MONEY:0999123456:5000":    mwuri.ErrUnsupportedVersion,
+		"mw:latest:TXN:AIRTEL_MONEY:0999123456:5000": mwuri.ErrUnsupportedVersion,
+		"mw:1.0:TXN:AIRTEL_MONEY:%ZZ:5000":           mwuri.ErrMalformed,
+	}
+	for in, want := range tests {
+		if _, err := mwuri.Parse(in); !errors.Is(err, want) {
+			t.Errorf("Parse(%q): got %v, want %v", in, err, want)
+		}
+	}
+
+	// Newer minor versions of the same major version are accepted.
+	if _, err := mwuri.Parse("mw:1.3:TXN:AIRTEL_MONEY:0999123456:5000"); err != nil {
+		t.Errorf("Parse of version 1.3 failed: %v", err)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	u := mwuri.New("AIRTEL_MONEY", "@mubas_cafe", mwjson.Kwacha(2500))
+	if err := u.Verify(pub); !errors.Is(err, mwuri.ErrUnsigned) {
+		t.Errorf("Verify unsigned: got %v, want ErrUnsigned", err)
+	}
+	if err := u.Sign(priv); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	s, err := u.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	parsed, err := mwuri.Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%q) failed: %v", s, err)
+	}
+	if err := parsed.Verify(pub); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+	if err := parsed.Verify(otherPub); !errors.Is(err, mwuri.ErrInvalidSignature) {
+		t.Errorf("Verify with wrong key: got %v, want ErrInvalidSignature", err)
+	}
+
+	parsed.Amount = mwjson.Kwacha(25000)
+	if err := parsed.Verify(pub); !errors.Is(err, mwuri.ErrInvalidSignature) {
+		t.Errorf("Verify after changing amount: got %v, want ErrInvalidSignature", err)
+	}
+
+	// Another implementation may encode the same fields differently; the
+	// signature covers its text, not ours.
+	foreign := "mw:1.0:TXN:AIRTEL_MONEY:%40mubas_cafe:2500.00"
+	sig := base64.RawURLEncoding.EncodeToString(ed25519.Sign(priv, []byte(foreign)))
+	parsed, err = mwuri.Parse(foreign + ":" + sig)
+	if err != nil {
+		t.Fatalf("Parse of foreign URI failed: %v", err)
+	}
+	if parsed.Recipient != "@mubas_cafe" || parsed.Amount != mwjson.Kwacha(2500) {
+		t.Errorf("Parsed foreign URI = %+v", parsed)
+	}
+	if err := parsed.Verify(pub); err != nil {
+		t.Errorf("Verify of foreign encoding failed: %v", err)
+	}
+}
+
+func TestTransactionConversion(t *testing.T) {
+	payer := mwjson.Participant{ID: "265991234567", IDType: mwjson.IDTypeMSISDN, Provider: mwjson.ProviderAirtelMoney}
+
+	u, err := mwuri.Parse("mw:1.0:TXN:TNM_MPAMBA:0888123456:1500")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	tx, err := u.Transaction(payer)
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if err := tx.Validate(); err != nil {
+		t.Errorf("Drafted transaction is invalid: %v", err)
+	}
+	if tx.Payload.Receiver.ID != "265888123456" || tx.Payload.Receiver.Provider != mwjson.ProviderTNMPamba || tx.Payload.Amount != mwjson.Kwacha(1500) {
+		t.Errorf("Unexpected receiver or amount: %+v", tx.Payload)
+	}
+	if tx.Payload.Type != mwjson.TxTypeP2P {
+		t.Errorf("Type = %s; want %s for a parsed URI", tx.Payload.Type, mwjson.TxTypeP2P)
+	}
+
+	back, err := mwuri.FromTransaction(tx)
+	if err != nil {
+		t.Fatalf("FromTransaction failed: %v", err)
+	}
+	if got := back.String(); got != "mw:1.0:TXN:TNM_MPAMBA:265888123456:1500" {
+		t.Errorf("FromTransaction = %q", got)
+	}
+
+	alias := mwuri.New("AIRTEL_MONEY", "@chifundo", mwjson.Money{})
+	tx, err = alias.Transaction(payer)
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if tx.Payload.Receiver.Alias != "@chifundo" || tx.Payload.Receiver.ID != "" {
+		t.Errorf("Alias recipient should be left for ALS resolution: %+v", tx.Payload.Receiver)
+	}
+}
+
+func TestUMQRConversion(t *testing.T) {
+	qr, err := umqr.GenerateMultiAccountQR("MUBAS Cafeteria", "Blantyre", umqr.MCCCampusCanteen, []umqr.MerchantAccount{
+		{AccountType: "AIRTEL_MONEY", Alias: "@mubas_cafe"},
+		{AccountType: "TNM_MPAMBA", Alias: "@mubas_cafe_tnm"},
+	}, mwjson.Kwacha(1200), "")
+	if err != nil {
+		t.Fatalf("GenerateMultiAccountQR failed: %v", err)
+	}
+	scanned, err := umqr.Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	u, err := mwuri.FromUMQR(scanned, "TNM_MPAMBA")
+	if err != nil {
+		t.Fatalf("FromUMQR failed: %v", err)
+	}
+	if got := u.String(); got != "mw:1.0:TXN:TNM_MPAMBA:@mubas_cafe_tnm:1200" {
+		t.Errorf("FromUMQR = %q", got)
+	}
+	payer := mwjson.Participant{ID: "265991234567", IDType: mwjson.IDTypeMSISDN, Provider: mwjson.ProviderTNMPamba}
+	if tx, err := u.Transaction(payer); err != nil || tx.Payload.Type != mwjson.TxTypeC2B {
+		t.Errorf("Transaction from merchant QR = %v; want type %s", err, mwjson.TxTypeC2B)
+	}
+
+	back, err := u.UMQR("MUBAS Cafeteria", "Blantyre", umqr.MCCCampusCanteen)
+	if err != nil {
+		t.Fatalf("UMQR failed: %v", err)
+	}
+	decoded, err := umqr.Decode(back)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if decoded.MerchantAccount.Alias != "@mubas_cafe_tnm" || decoded.Amount != mwjson.Kwacha(1200) {
+		t.Errorf("Unexpected UMQR: %+v", decoded)
+	}
+
+	if _, err := mwuri.New("AIRTEL_MONEY", "0999123456", mwjson.Money{}).UMQR("Shop", "Zomba", umqr.MCCMarketVendor); err == nil {
+		t.Error("Expected error for MSISDN recipient in UMQR, got nil")
+	}
+}
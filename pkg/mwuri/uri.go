@@ -0,0 +1,270 @@
+// Package mwuri implements the compact "mw:" payment URI read by the mobile
+// app:
+//
+//	mw:1.0:TXN:AIRTEL_MONEY:@chifundo:5000:<sig>
+//
+// Fields are separated by colons. Text fields are percent-encoded, so a
+// colon inside a value never splits a field. The optional last field is an
+// unpadded base64url Ed25519 signature over everything before it.
+package mwuri
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
+)
+
+// Scheme is the URI prefix, without the colon.
+const Scheme = "mw"
+
+// Version is the URI format version written by Encode. Parse accepts any
+// minor version of the same major version.
+const Version = "1.0"
+
+// KindTransaction is the only URI kind defined so far: a payment request.
+const KindTransaction = "TXN"
+
+// Parsing and signing errors. Callers can match them with errors.Is.
+var (
+	ErrMalformed          = errors.New("mwuri: malformed URI")
+	ErrUnsupportedVersion = errors.New("mwuri: unsupported version")
+	ErrUnsigned           = errors.New("mwuri: URI is not signed")
+	ErrInvalidSignature   = errors.New("mwuri: signature verification failed")
+)
+
+// amountPattern matches a non-negative MWK amount with at most 2 decimals.
+var amountPattern = regexp.MustCompile(`^\d+(\.\d{1,2})?$`)
+
+// URI is a parsed "mw:" payment URI.
+type URI struct {
+	Version   string
+	Kind      string
+	Provider  string
+	Recipient string       // Alias ("@chifundo") or MSISDN
+	Amount    mwjson.Money // Zero lets the payer enter the amount
+	Signature []byte       // Ed25519; nil when unsigned
+
+	// Type is the MW-JSON payment type Transaction drafts, P2P when empty.
+	// It is not part of the URI text; FromUMQR and FromTransaction carry it
+	// over from their source.
+	Type mwjson.TxType
+
+	// signed is the text before the signature exactly as Parse received
+	// it. Other implementations may encode the same fields differently,
+	// e.g. "5000.00" for "5000", and the signature covers their bytes.
+	signed string
+}
+
+// New returns an unsigned payment URI for the current version.
+func New(provider, recipient string, amount mwjson.Money) *URI {
+	return &URI{
+		Version:   Version,
+		Kind:      KindTransaction,
+		Provider:  provider,
+		Recipient: recipient,
+		Amount:    amount,
+	}
+}
+
+// Validate checks the fields before encoding.
+func (u *URI) Validate() error {
+	if err := checkVersion(u.Version); err != nil {
+		return err
+	}
+	if u.Kind != KindTransaction {
+		return fmt.Errorf("%w: unknown kind %q", ErrMalformed, u.Kind)
+	}
+	if u.Provider == "" {
+		return fmt.Errorf("%w: missing provider", ErrMalformed)
+	}
+	if u.Recipient == "" {
+		return fmt.Errorf("%w: missing recipient", ErrMalformed)
+	}
+	if u.Amount.Cmp(mwjson.Money{}) < 0 {
+		return fmt.Errorf("%w: negative amount %s", ErrMalformed, u.Amount)
+	}
+	return nil
+}
+
+// Encode validates the URI and returns its string form.
+func (u *URI) Encode() (string, error) {
+	if err := u.Validate(); err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// String returns the URI in canonical form without validating it. Use
+// Encode for output that leaves the server.
+func (u *URI) String() string {
+	s := u.canonical()
+	if len(u.Signature) > 0 {
+		s += ":" + base64.RawURLEncoding.EncodeToString(u.Signature)
+	}
+	return s
+}
+
+// SignedContent returns the part of the URI covered by the signature: for
+// a parsed URI, everything before the signature exactly as received;
+// otherwise the canonical encoding of the fields.
+func (u *URI) SignedContent() string {
+	if u.signed != "" {
+		return u.signed
+	}
+	return u.canonical()
+}
+
+// canonical encodes every field before the signature the way this package
+// writes URIs.
+func (u *URI) canonical() string {
+	return strings.Join([]string{
+		Scheme,
+		escape(u.Version),
+		escape(u.Kind),
+		escape(u.Provider),
+		escape(u.Recipient),
+		formatAmount(u.Amount),
+	}, ":")
+}
+
+// Parse decodes a "mw:" URI. It does not verify the signature.
+func Parse(s string) (*URI, error) {
+	s = strings.TrimSpace(s)
+	fields := strings.Split(s, ":")
+	if fields[0] != Scheme {
+		return nil, fmt.Errorf("%w: scheme must be %q", ErrMalformed, Scheme)
+	}
+	if len(fields) != 6 && len(fields) != 7 {
+		return nil, fmt.Errorf("%w: expected 6 or 7 fields, got %d", ErrMalformed, len(fields))
+	}
+
+	values := make([]string, 4)
+	for i := range values {
+		v, err := url.PathUnescape(fields[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %d: %v", ErrMalformed, i+1, err)
+		}
+		values[i] = v
+	}
+	if err := checkVersion(values[0]); err != nil {
+		return nil, err
+	}
+
+	u := &URI{
+		Version:   values[0],
+		Kind:      values[1],
+		Provider:  values[2],
+		Recipient: values[3],
+	}
+	if a := fields[5]; a != "" {
+		if !amountPattern.MatchString(a) {
+			return nil, fmt.Errorf("%w: invalid amount %q", ErrMalformed, a)
+		}
+		amount, err := mwjson.ParseMoney(a)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid amount %q", ErrMalformed, a)
+		}
+		u.Amount = amount
+	}
+	if len(fields) == 7 {
+		sig, err := base64.RawURLEncoding.DecodeString(fields[6])
+		if err != nil || len(sig) != ed25519.SignatureSize {
+			return nil, fmt.Errorf("%w: invalid signature encoding", ErrMalformed)
+		}
+		u.Signature = sig
+		u.signed = s[:strings.LastIndexByte(s, ':')]
+	}
+
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Sign signs the URI with the recipient's key, replacing any previous signature.
+func (u *URI) Sign(priv ed25519.PrivateKey) error {
+	if err := u.Validate(); err != nil {
+		return err
+	}
+	u.signed = ""
+	u.Signature = ed25519.Sign(priv, []byte(u.canonical()))
+	return nil
+}
+
+// Verify checks the signature against the recipient's public key.
+func (u *URI) Verify(pub ed25519.PublicKey) error {
+	if len(u.Signature) == 0 {
+		return ErrUnsigned
+	}
+	if u.signed != "" {
+		// Fields changed since Parse are not covered by the received signature.
+		orig, err := Parse(u.signed)
+		if err != nil || orig.Version != u.Version || orig.Kind != u.Kind || orig.Provider != u.Provider ||
+			orig.Recipient != u.Recipient || orig.Amount != u.Amount {
+			return ErrInvalidSignature
+		}
+	}
+	if len(pub) != ed25519.PublicKeySize || !ed25519.Verify(pub, []byte(u.SignedContent()), u.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// checkVersion accepts any version with the same major number as Version.
+func checkVersion(v string) error {
+	major, _, _ := strings.Cut(Version, ".")
+	got, minor, ok := strings.Cut(v, ".")
+	if !ok || got != major || minor == "" || !isDigits(minor) {
+		return fmt.Errorf("%w: %q", ErrUnsupportedVersion, v)
+	}
+	return nil
+}
+
+// formatAmount writes whole kwacha without decimals and anything else with
+// exactly two. A zero amount is written as an empty field.
+func formatAmount(amount mwjson.Money) string {
+	switch {
+	case amount.IsZero():
+		return ""
+	case amount.IsWhole():
+		return strconv.FormatInt(amount.Kwacha(), 10)
+	default:
+		return amount.String()
+	}
+}
+
+// escape percent-encodes every byte outside the RFC 3986 unreserved set,
+// plus "@" and "+" which are common in aliases and MSISDNs.
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isUnreserved(c) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		strings.IndexByte("-._~@+", c) >= 0
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
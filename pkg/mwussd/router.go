@@ -41,6 +41,11 @@ func (r *Router) GenerateSession(txn *mwjson.Transaction, pin string) ([]UssdSte
 	// Route based on sender's provider (who is initiating the USSD?)
 	senderProvider := txn.Payload.Sender.Provider
 
+	// USSD menus only accept whole kwacha; never round the amount silently.
+	if !txn.Payload.Amount.IsWhole() {
+		return nil, fmt.Errorf("amount %s has tambala; USSD accepts whole kwacha only", txn.Payload.Amount)
+	}
+
 	switch senderProvider {
 	case mwjson.ProviderAirtelMoney:
 		return r.generateAirtelSession(txn, pin)
@@ -76,8 +81,8 @@ func (r *Router) generateAirtelSession(txn *mwjson.Transaction, pin string) ([]U
 		// 4. Enter Amount
 		{
 			Action:  ActionReply,
-			Content: strconv.FormatFloat(txn.Payload.Amount, 'f', 0, 64), // No decimals for USSD usually
-			Expect:  "(?i)Enter.*PIN",                                    // Match "Enter PIN"
+			Content: strconv.FormatInt(txn.Payload.Amount.Kwacha(), 10), // Whole kwacha, checked above
+			Expect:  "(?i)Enter.*PIN",                                   // Match "Enter PIN"
 		},
 		// 5. Enter PIN
 		{
@@ -114,7 +119,7 @@ func (r *Router) generateTNMSession(txn *mwjson.Transaction, pin string) ([]Ussd
 		// 4. Enter Amount
 		{
 			Action:  ActionReply,
-			Content: strconv.FormatFloat(txn.Payload.Amount, 'f', 0, 64),
+			Content: strconv.FormatInt(txn.Payload.Amount.Kwacha(), 10),
 			Expect:  "(?i)Enter.*PIN",
 		},
 		// 5. Enter PIN
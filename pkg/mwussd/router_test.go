@@ -10,7 +10,7 @@ func TestGenerateAirtelSession(t *testing.T) {
 	router := NewRouter()
 	txn := &mwjson.Transaction{
 		Payload: mwjson.Payload{
-			Amount: 5000,
+			Amount: mwjson.Kwacha(5000),
 			Sender: mwjson.Participant{
 				Provider: mwjson.ProviderAirtelMoney,
 			},
@@ -42,7 +42,7 @@ func TestGenerateTNMSession(t *testing.T) {
 	router := NewRouter()
 	txn := &mwjson.Transaction{
 		Payload: mwjson.Payload{
-			Amount: 2500,
+			Amount: mwjson.Kwacha(2500),
 			Sender: mwjson.Participant{
 				Provider: mwjson.ProviderTNMPamba,
 			},
@@ -65,3 +65,22 @@ func TestGenerateTNMSession(t *testing.T) {
 		t.Errorf("Step 1 failed: Expected DIAL *444#, got %v %v", steps[0].Action, steps[0].Content)
 	}
 }
+
+func TestFractionalAmountRejected(t *testing.T) {
+	router := NewRouter()
+	txn := &mwjson.Transaction{
+		Payload: mwjson.Payload{
+			Amount: mwjson.Tambala(250050), // MWK 2500.50
+			Sender: mwjson.Participant{
+				Provider: mwjson.ProviderAirtelMoney,
+			},
+			Receiver: mwjson.Participant{
+				ID: "0999123456",
+			},
+		},
+	}
+
+	if _, err := router.GenerateSession(txn, "1234"); err == nil {
+		t.Error("Expected error for amount with tambala, got nil")
+	}
+}
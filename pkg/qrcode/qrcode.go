@@ -0,0 +1,497 @@
+// Package qrcode renders UMQR payloads as QR Code symbols (ISO/IEC 18004)
+// using only the standard library, so it builds on offline campus servers.
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Level is the error correction level of a symbol.
+type Level int
+
+const (
+	Low      Level = iota // Recovers ~7% of codewords
+	Medium                // Recovers ~15% of codewords
+	Quartile              // Recovers ~25% of codewords
+	High                  // Recovers ~30% of codewords
+)
+
+// String returns the single-letter name used in the QR specification.
+func (l Level) String() string {
+	switch l {
+	case Low:
+		return "L"
+	case Medium:
+		return "M"
+	case Quartile:
+		return "Q"
+	case High:
+		return "H"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// formatBits are the two-bit codes written in the format information.
+func (l Level) formatBits() int {
+	return [...]int{1, 0, 3, 2}[l]
+}
+
+// Rendering defaults.
+const (
+	DefaultQuietZone  = 4 // Minimum border required by ISO/IEC 18004
+	DefaultModuleSize = 8
+	MaxLogoSize       = 0.2 // Largest logo width, as a fraction of the symbol width
+)
+
+// ErrDataTooLong is returned when the payload does not fit in a version 40 symbol.
+var ErrDataTooLong = errors.New("qrcode: data too long")
+
+// Options controls how a symbol is encoded and drawn.
+type Options struct {
+	Level      Level   // Error correction level; forced to High when LogoSize > 0
+	QuietZone  int     // Border in modules; 0 means DefaultQuietZone
+	ModuleSize int     // Pixels (PNG) or user units (SVG) per module; 0 means DefaultModuleSize
+	LogoSize   float64 // Fraction of the symbol width left blank for a center logo, up to MaxLogoSize
+}
+
+// Symbol is an encoded QR Code matrix.
+type Symbol struct {
+	Version int
+	Level   Level
+	Mask    int
+
+	size       int
+	modules    [][]bool // [y][x], true is dark
+	isFunction [][]bool
+	opts       Options
+}
+
+// New encodes data in byte mode using the smallest version that fits.
+func New(data string, opts Options) (*Symbol, error) {
+	if opts.Level < Low || opts.Level > High {
+		return nil, fmt.Errorf("qrcode: invalid error correction level %d", opts.Level)
+	}
+	if opts.LogoSize < 0 || opts.LogoSize > MaxLogoSize {
+		return nil, fmt.Errorf("qrcode: logo size %.2f outside 0-%.2f", opts.LogoSize, MaxLogoSize)
+	}
+	if opts.LogoSize > 0 {
+		// The logo hides modules; only level H has enough redundancy to recover them.
+		opts.Level = High
+	}
+	if opts.QuietZone <= 0 {
+		opts.QuietZone = DefaultQuietZone
+	}
+	if opts.ModuleSize <= 0 {
+		opts.ModuleSize = DefaultModuleSize
+	}
+
+	bytes := []byte(data)
+	version := 0
+	for v := 1; v <= 40; v++ {
+		if dataBits(v, len(bytes)) <= numDataCodewords(v, opts.Level)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("%w: %d bytes at level %s", ErrDataTooLong, len(bytes), opts.Level)
+	}
+
+	s := &Symbol{Version: version, Level: opts.Level, size: version*4 + 17, opts: opts}
+	s.modules = newGrid(s.size)
+	s.isFunction = newGrid(s.size)
+
+	s.drawFunctionPatterns()
+	s.drawCodewords(addEccAndInterleave(encodeData(bytes, version, opts.Level), version, opts.Level))
+	s.chooseMask()
+	return s, nil
+}
+
+// Size returns the width of the symbol in modules, excluding the quiet zone.
+func (s *Symbol) Size() int {
+	return s.size
+}
+
+// Module reports whether the module at column x, row y is dark.
+// Coordinates outside the symbol are light.
+func (s *Symbol) Module(x, y int) bool {
+	if x < 0 || y < 0 || x >= s.size || y >= s.size {
+		return false
+	}
+	return s.modules[y][x]
+}
+
+// dataBits is the length of the byte-mode bit stream before padding.
+func dataBits(version, n int) int {
+	countBits := 8
+	if version > 9 {
+		countBits = 16
+	}
+	return 4 + countBits + 8*n
+}
+
+// encodeData builds the padded data codewords for byte mode.
+func encodeData(data []byte, version int, level Level) []byte {
+	var bb bitBuffer
+	bb.append(0x4, 4) // Byte mode indicator
+	if version > 9 {
+		bb.append(len(data), 16)
+	} else {
+		bb.append(len(data), 8)
+	}
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+
+	capacity := numDataCodewords(version, level) * 8
+	bb.append(0, min(4, capacity-len(bb))) // Terminator
+	bb.append(0, (8-len(bb)%8)%8)          // Byte align
+	for pad := 0xEC; len(bb) < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	out := make([]byte, len(bb)/8)
+	for i, bit := range bb {
+		if bit {
+			out[i>>3] |= 1 << (7 - uint(i&7))
+		}
+	}
+	return out
+}
+
+type bitBuffer []bool
+
+func (bb *bitBuffer) append(val, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*bb = append(*bb, (val>>uint(i))&1 != 0)
+	}
+}
+
+// addEccAndInterleave splits data into blocks, appends Reed-Solomon
+// codewords to each and interleaves them into the final sequence.
+func addEccAndInterleave(data []byte, version int, level Level) []byte {
+	numBlocks := numErrorCorrectionBlocks[level][version]
+	eccLen := eccCodewordsPerBlock[level][version]
+	rawCodewords := numRawDataModules(version) / 8
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortBlockLen := rawCodewords / numBlocks
+
+	divisor := reedSolomonDivisor(eccLen)
+	blocks := make([][]byte, numBlocks)
+	for i, k := 0, 0; i < numBlocks; i++ {
+		n := shortBlockLen - eccLen
+		if i >= numShortBlocks {
+			n++
+		}
+		block := append([]byte(nil), data[k:k+n]...)
+		k += n
+		ecc := reedSolomonRemainder(block, divisor)
+		if i < numShortBlocks {
+			block = append(block, 0) // Placeholder so all blocks have equal length
+		}
+		blocks[i] = append(block, ecc...)
+	}
+
+	out := make([]byte, 0, rawCodewords)
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			if i != shortBlockLen-eccLen || j >= numShortBlocks {
+				out = append(out, block[i])
+			}
+		}
+	}
+	return out
+}
+
+func (s *Symbol) setFunction(x, y int, dark bool) {
+	s.modules[y][x] = dark
+	s.isFunction[y][x] = true
+}
+
+// drawFunctionPatterns draws the finder, timing and alignment patterns and
+// reserves the format and version areas.
+func (s *Symbol) drawFunctionPatterns() {
+	for i := 0; i < s.size; i++ {
+		s.setFunction(6, i, i%2 == 0)
+		s.setFunction(i, 6, i%2 == 0)
+	}
+
+	s.drawFinder(3, 3)
+	s.drawFinder(s.size-4, 3)
+	s.drawFinder(3, s.size-4)
+
+	pos := alignmentPositions(s.Version)
+	last := len(pos) - 1
+	for i := range pos {
+		for j := range pos {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue // Overlaps a finder pattern
+			}
+			s.drawAlignment(pos[i], pos[j])
+		}
+	}
+
+	s.drawFormatBits(0) // Placeholder, rewritten once the mask is chosen
+	s.drawVersion()
+}
+
+func (s *Symbol) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= s.size || y >= s.size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			s.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (s *Symbol) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			s.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+func (s *Symbol) drawFormatBits(mask int) {
+	data := s.Level.formatBits()<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 != 0 }
+
+	// First copy, around the top-left finder
+	for i := 0; i <= 5; i++ {
+		s.setFunction(8, i, bit(i))
+	}
+	s.setFunction(8, 7, bit(6))
+	s.setFunction(8, 8, bit(7))
+	s.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		s.setFunction(14-i, 8, bit(i))
+	}
+
+	// Second copy, split between the other two finders
+	for i := 0; i < 8; i++ {
+		s.setFunction(s.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		s.setFunction(8, s.size-15+i, bit(i))
+	}
+	s.setFunction(8, s.size-8, true) // Always-dark module
+}
+
+func (s *Symbol) drawVersion() {
+	if s.Version < 7 {
+		return
+	}
+	rem := s.Version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := s.Version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := (bits>>uint(i))&1 != 0
+		a, b := s.size-11+i%3, i/3
+		s.setFunction(a, b, dark)
+		s.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the data bits in the zigzag order of the specification.
+func (s *Symbol) drawCodewords(data []byte) {
+	i := 0
+	for right := s.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // Skip the vertical timing pattern
+		}
+		for vert := 0; vert < s.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = s.size - 1 - vert // Upward column
+				}
+				if !s.isFunction[y][x] && i < len(data)*8 {
+					s.modules[y][x] = (data[i>>3]>>(7-uint(i&7)))&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask XORs the data modules with one of the eight mask patterns.
+// Applying the same mask twice restores the original.
+func (s *Symbol) applyMask(mask int) {
+	for y := 0; y < s.size; y++ {
+		for x := 0; x < s.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !s.isFunction[y][x] {
+				s.modules[y][x] = !s.modules[y][x]
+			}
+		}
+	}
+}
+
+// chooseMask applies the mask with the lowest penalty score.
+func (s *Symbol) chooseMask() {
+	best, bestPenalty := 0, math.MaxInt
+	for mask := 0; mask < 8; mask++ {
+		s.applyMask(mask)
+		s.drawFormatBits(mask)
+		if p := s.penalty(); p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		s.applyMask(mask)
+	}
+	s.Mask = best
+	s.applyMask(best)
+	s.drawFormatBits(best)
+}
+
+// penalty scores the current matrix using the four rules of ISO/IEC 18004.
+func (s *Symbol) penalty() int {
+	n := s.size
+	at := func(x, y int, transpose bool) bool {
+		if transpose {
+			return s.modules[x][y]
+		}
+		return s.modules[y][x]
+	}
+	finderA := []bool{true, false, true, true, true, false, true, false, false, false, false}
+	finderB := []bool{false, false, false, false, true, false, true, true, true, false, true}
+
+	result := 0
+	for _, transpose := range []bool{false, true} {
+		for y := 0; y < n; y++ {
+			// Rule 1: runs of five or more same-coloured modules
+			run := 1
+			for x := 1; x < n; x++ {
+				if at(x, y, transpose) == at(x-1, y, transpose) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					result += 3 + run - 5
+				}
+				run = 1
+			}
+			if run >= 5 {
+				result += 3 + run - 5
+			}
+
+			// Rule 3: finder-like 1:1:3:1:1 patterns next to four light modules
+			for x := 0; x+len(finderA) <= n; x++ {
+				matchA, matchB := true, true
+				for k := range finderA {
+					v := at(x+k, y, transpose)
+					matchA = matchA && v == finderA[k]
+					matchB = matchB && v == finderB[k]
+				}
+				if matchA {
+					result += 40
+				}
+				if matchB {
+					result += 40
+				}
+			}
+		}
+	}
+
+	// Rule 2: 2x2 blocks of one colour
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if s.modules[y][x] {
+				dark++
+			}
+			if x < n-1 && y < n-1 {
+				c := s.modules[y][x]
+				if c == s.modules[y][x+1] && c == s.modules[y+1][x] && c == s.modules[y+1][x+1] {
+					result += 3
+				}
+			}
+		}
+	}
+
+	// Rule 4: balance of dark and light modules
+	total := n * n
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return result + k*10
+}
+
+// numRawDataModules returns the number of modules available for data and
+// error correction codewords, after removing all function patterns.
+func numRawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		numAlign := version/7 + 2
+		result -= (25*numAlign-10)*numAlign - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+// numDataCodewords returns the number of 8-bit data codewords for a version and level.
+func numDataCodewords(version int, level Level) int {
+	return numRawDataModules(version)/8 - eccCodewordsPerBlock[level][version]*numErrorCorrectionBlocks[level][version]
+}
+
+// alignmentPositions returns the centre coordinates of the alignment patterns.
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	numAlign := version/7 + 2
+	step := (version*8 + numAlign*3 + 5) / (numAlign*4 - 4) * 2
+	size := version*4 + 17
+	pos := make([]int, numAlign)
+	pos[0] = 6
+	for i, p := numAlign-1, size-7; i >= 1; i, p = i-1, p-step {
+		pos[i] = p
+	}
+	return pos
+}
+
+func newGrid(n int) [][]bool {
+	g := make([][]bool, n)
+	for i := range g {
+		g[i] = make([]bool, n)
+	}
+	return g
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
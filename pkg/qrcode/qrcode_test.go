@@ -0,0 +1,107 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// "HELLO WORLD" 1-M data codewords and their known error correction codewords.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	got := reedSolomonRemainder(data, reedSolomonDivisor(len(want)))
+	if !bytes.Equal(got, want) {
+		t.Errorf("reedSolomonRemainder() = %v; want %v", got, want)
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	tests := []struct {
+		version int
+		level   Level
+		want    int
+	}{
+		{1, Low, 19},
+		{1, Medium, 16},
+		{1, Quartile, 13},
+		{1, High, 9},
+		{10, Medium, 216},
+		{40, Low, 2956},
+		{40, High, 1276},
+	}
+
+	for _, tt := range tests {
+		if got := numDataCodewords(tt.version, tt.level); got != tt.want {
+			t.Errorf("numDataCodewords(%d, %s) = %d; want %d", tt.version, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	payload := "00020101021126440016MW.GOV.NATSWITCH0112AIRTEL_MONEY0212@mubas_cafe5204541153034545802MW5915MUBAS Cafeteria6008Blantyre63041D3A"
+
+	s, err := New(payload, Options{Level: Medium})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if s.Size() != s.Version*4+17 {
+		t.Errorf("Size() = %d; want %d", s.Size(), s.Version*4+17)
+	}
+
+	// Finder pattern corners are dark, the separator next to them is light.
+	if !s.Module(0, 0) || !s.Module(s.Size()-1, 0) || !s.Module(0, s.Size()-1) {
+		t.Error("Expected dark finder pattern corners")
+	}
+	if s.Module(7, 0) || s.Module(0, 7) {
+		t.Error("Expected light finder separators")
+	}
+
+	if _, err := New(strings.Repeat("A", 1300), Options{Level: High}); err == nil {
+		t.Error("Expected ErrDataTooLong for 1300 bytes at level H, got nil")
+	}
+}
+
+func TestLogoForcesHighLevel(t *testing.T) {
+	s, err := New("00020101021126", Options{Level: Low, LogoSize: 0.15})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if s.Level != High {
+		t.Errorf("Level = %s; want H when a logo is reserved", s.Level)
+	}
+
+	if _, err := New("00020101021126", Options{LogoSize: 0.5}); err == nil {
+		t.Error("Expected error for logo larger than MaxLogoSize, got nil")
+	}
+}
+
+func TestRender(t *testing.T) {
+	s, err := New("00020101021126", Options{ModuleSize: 2, QuietZone: 2})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.PNG(&buf); err != nil {
+		t.Fatalf("PNG failed: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("PNG output does not decode: %v", err)
+	}
+	if want := (s.Size() + 4) * 2; img.Bounds().Dx() != want {
+		t.Errorf("PNG width = %d; want %d", img.Bounds().Dx(), want)
+	}
+
+	buf.Reset()
+	if err := s.SVG(&buf); err != nil {
+		t.Fatalf("SVG failed: %v", err)
+	}
+	svg := buf.String()
+	if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") {
+		t.Errorf("Malformed SVG: %.60s...", svg)
+	}
+}
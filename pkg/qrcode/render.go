@@ -0,0 +1,83 @@
+package qrcode
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// logoBounds returns the module range [lo, hi) kept blank for a centre logo.
+// The range is centred and has the same parity as the symbol so it is symmetric.
+func (s *Symbol) logoBounds() (lo, hi int) {
+	if s.opts.LogoSize <= 0 {
+		return 0, 0
+	}
+	n := int(math.Ceil(float64(s.size) * s.opts.LogoSize))
+	if n%2 != s.size%2 {
+		n++
+	}
+	lo = (s.size - n) / 2
+	return lo, lo + n
+}
+
+// drawn reports whether a dark module should be painted, honouring the logo area.
+func (s *Symbol) drawn(x, y int) bool {
+	lo, hi := s.logoBounds()
+	if x >= lo && x < hi && y >= lo && y < hi {
+		return false
+	}
+	return s.modules[y][x]
+}
+
+// Image returns the symbol, including its quiet zone, as a two-colour image.
+func (s *Symbol) Image() image.Image {
+	scale, quiet := s.opts.ModuleSize, s.opts.QuietZone
+	width := (s.size + 2*quiet) * scale
+
+	img := image.NewPaletted(image.Rect(0, 0, width, width), color.Palette{color.White, color.Black})
+	for y := 0; y < s.size; y++ {
+		for x := 0; x < s.size; x++ {
+			if !s.drawn(x, y) {
+				continue
+			}
+			for py := 0; py < scale; py++ {
+				row := (y+quiet)*scale + py
+				for px := 0; px < scale; px++ {
+					img.SetColorIndex((x+quiet)*scale+px, row, 1)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// PNG writes the symbol as a PNG image.
+func (s *Symbol) PNG(w io.Writer) error {
+	return png.Encode(w, s.Image())
+}
+
+// SVG writes the symbol as a standalone SVG document. Dark modules are drawn
+// as a single path so the output stays small enough for print pipelines.
+func (s *Symbol) SVG(w io.Writer) error {
+	scale, quiet := s.opts.ModuleSize, s.opts.QuietZone
+	units := s.size + 2*quiet
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		units*scale, units*scale, units, units)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="#FFFFFF"/>`, units, units)
+	bw.WriteString(`<path fill="#000000" d="`)
+	for y := 0; y < s.size; y++ {
+		for x := 0; x < s.size; x++ {
+			if s.drawn(x, y) {
+				fmt.Fprintf(bw, "M%d %dh1v1h-1z", x+quiet, y+quiet)
+			}
+		}
+	}
+	bw.WriteString(`"/></svg>`)
+	return bw.Flush()
+}
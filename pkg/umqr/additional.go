@@ -0,0 +1,136 @@
+package umqr
+
+import "strings"
+
+// Additional data (Tag 62) sub-tags.
+const (
+	SubTagBillNumber          = "01"
+	SubTagMobileNumber        = "02"
+	SubTagStoreLabel          = "03"
+	SubTagLoyaltyNumber       = "04"
+	SubTagReferenceLabel      = "05"
+	SubTagCustomerLabel       = "06"
+	SubTagTerminalLabel       = "07"
+	SubTagPurpose             = "08"
+	SubTagConsumerDataRequest = "09"
+)
+
+// PromptPayer is the EMVCo value that asks the payer app to supply a field,
+// e.g. a loyalty number the customer types in at the till.
+const PromptPayer = "***"
+
+// Consumer data request flags (sub-tag 09).
+const (
+	RequestAddress = "A"
+	RequestMobile  = "M"
+	RequestEmail   = "E"
+)
+
+// maxAdditionalDataLen is the EMVCo limit for sub-tags 01-08.
+const maxAdditionalDataLen = 25
+
+// AdditionalData is the typed form of the additional data template (Tag 62).
+type AdditionalData struct {
+	BillNumber     string // 01, invoice or bill to pay
+	MobileNumber   string // 02, e.g. for airtime top-ups
+	StoreLabel     string // 03, branch or outlet
+	LoyaltyNumber  string // 04
+	ReferenceLabel string // 05, merchant's own reference
+	CustomerLabel  string // 06, e.g. student number for school fees
+	TerminalLabel  string // 07, till or POS ID
+	Purpose        string // 08, purpose of transaction
+
+	// 09, ask the payer app to send these details with the payment
+	RequestAddress bool
+	RequestMobile  bool
+	RequestEmail   bool
+}
+
+// fields pairs each string sub-tag with its value, in sub-tag order.
+func (a AdditionalData) fields() []TLV {
+	return []TLV{
+		{Tag: SubTagBillNumber, Value: a.BillNumber},
+		{Tag: SubTagMobileNumber, Value: a.MobileNumber},
+		{Tag: SubTagStoreLabel, Value: a.StoreLabel},
+		{Tag: SubTagLoyaltyNumber, Value: a.LoyaltyNumber},
+		{Tag: SubTagReferenceLabel, Value: a.ReferenceLabel},
+		{Tag: SubTagCustomerLabel, Value: a.CustomerLabel},
+		{Tag: SubTagTerminalLabel, Value: a.TerminalLabel},
+		{Tag: SubTagPurpose, Value: a.Purpose},
+	}
+}
+
+// consumerDataRequest returns the sub-tag 09 value, e.g. "ME".
+func (a AdditionalData) consumerDataRequest() string {
+	var b strings.Builder
+	if a.RequestAddress {
+		b.WriteString(RequestAddress)
+	}
+	if a.RequestMobile {
+		b.WriteString(RequestMobile)
+	}
+	if a.RequestEmail {
+		b.WriteString(RequestEmail)
+	}
+	return b.String()
+}
+
+// IsZero reports whether no field is set.
+func (a AdditionalData) IsZero() bool {
+	return a == AdditionalData{}
+}
+
+// Validate checks the EMVCo length limits of each sub-tag.
+func (a AdditionalData) Validate() error {
+	for _, f := range a.fields() {
+		if err := checkLength(TagAdditionalData+"."+f.Tag, f.Value, maxAdditionalDataLen); err != nil {
+			return err
+		}
+	}
+	return a.Template().Validate()
+}
+
+// Template builds the Tag 62 template. Empty fields are omitted.
+func (a AdditionalData) Template() *Template {
+	t := NewTemplate(TagAdditionalData)
+	for _, f := range a.fields() {
+		if f.Value != "" {
+			t.Set(f.Tag, f.Value)
+		}
+	}
+	if req := a.consumerDataRequest(); req != "" {
+		t.Set(SubTagConsumerDataRequest, req)
+	}
+	return t
+}
+
+// SetAdditionalData validates the additional data and stores it as Tag 62.
+func (e *Encoder) SetAdditionalData(a AdditionalData) error {
+	if a.IsZero() {
+		delete(e.templates, TagAdditionalData)
+		return nil
+	}
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	e.SetTemplate(a.Template())
+	return nil
+}
+
+// parseAdditionalData reads a decoded Tag 62 template.
+func parseAdditionalData(t *Template) AdditionalData {
+	req := subTag(t, SubTagConsumerDataRequest)
+	return AdditionalData{
+		BillNumber:     subTag(t, SubTagBillNumber),
+		MobileNumber:   subTag(t, SubTagMobileNumber),
+		StoreLabel:     subTag(t, SubTagStoreLabel),
+		LoyaltyNumber:  subTag(t, SubTagLoyaltyNumber),
+		ReferenceLabel: subTag(t, SubTagReferenceLabel),
+		CustomerLabel:  subTag(t, SubTagCustomerLabel),
+		TerminalLabel:  subTag(t, SubTagTerminalLabel),
+		Purpose:        subTag(t, SubTagPurpose),
+		RequestAddress: strings.Contains(req, RequestAddress),
+		RequestMobile:  strings.Contains(req, RequestMobile),
+		RequestEmail:   strings.Contains(req, RequestEmail),
+	}
+}
@@ -0,0 +1,356 @@
+package umqr
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwals"
+)
+
+// ErrReplay is returned when a consumer-presented QR reuses a counter.
+var ErrReplay = errors.New("umqr: consumer QR counter already used")
+
+// Consumer-presented mode (CPM) follows the EMVCo CPM layout: a base64
+// BER-TLV payload holding a payload format indicator (85) and one
+// application template (61). Unlike merchant-presented payloads, tags are
+// hex and lengths count bytes.
+const (
+	CPMFormat           = "CPV01"
+	CPMApplicationID    = "A00000045401" // Tag 4F, hex
+	CPMApplicationLabel = "MW-UMQR"      // Tag 50
+
+	// CPMTokenSize is the length in bytes of the one-time token.
+	CPMTokenSize = 16
+
+	// MaxConsumerQRTTL bounds how long a consumer QR may stay valid.
+	MaxConsumerQRTTL = 5 * time.Minute
+)
+
+// BER-TLV tags used in consumer-presented payloads, in payload order.
+const (
+	cpmTagFormat     = "85"
+	cpmTagAppTmpl    = "61"
+	cpmTagAID        = "4F"
+	cpmTagLabel      = "50"
+	cpmTagAlias      = "5F20" // Cardholder name, carries the payer alias
+	cpmTagCounter    = "9F36" // Application transaction counter
+	cpmTagToken      = "9F7C" // Customer exclusive data, carries the one-time token
+	cpmTagExpiryDate = "9A"   // YYMMDD, BCD, UTC
+	cpmTagExpiryTime = "9F21" // hhmmss, BCD, UTC
+	cpmTagSignature  = "9F26" // Application cryptogram, carries the Ed25519 signature
+)
+
+// ConsumerQR is a payer-presented, single-use payment token.
+type ConsumerQR struct {
+	Alias     string    // Payer's MW-ALS alias
+	Counter   uint16    // Must increase with every QR the device shows
+	Token     []byte    // Random one-time token
+	Expiry    time.Time // UTC, whole seconds
+	Signature []byte    // Ed25519 by the payer's device key
+}
+
+// GenerateConsumerQR builds and signs a consumer-presented QR for alias. The
+// device must persist counter and pass a higher value every time.
+func GenerateConsumerQR(alias string, counter uint16, ttl time.Duration, key ed25519.PrivateKey) (string, error) {
+	if ttl <= 0 || ttl > MaxConsumerQRTTL {
+		return "", fmt.Errorf("%w: TTL must be between 0 and %s", ErrInvalidValue, MaxConsumerQRTTL)
+	}
+	c := &ConsumerQR{
+		Alias:   alias,
+		Counter: counter,
+		Token:   make([]byte, CPMTokenSize),
+		Expiry:  time.Now().UTC().Add(ttl).Truncate(time.Second),
+	}
+	if _, err := rand.Read(c.Token); err != nil {
+		return "", err
+	}
+	if err := c.validate(); err != nil {
+		return "", err
+	}
+	c.Signature = ed25519.Sign(key, c.signedContent())
+	return c.String(), nil
+}
+
+// String returns the base64 payload to render as a QR.
+func (c *ConsumerQR) String() string {
+	format := berTLV(cpmTagFormat, []byte(CPMFormat))
+	app := append(c.appData(), berTLV(cpmTagSignature, c.Signature)...)
+	return base64.StdEncoding.EncodeToString(append(format, berTLV(cpmTagAppTmpl, app)...))
+}
+
+// DecodeConsumerQR parses a scanned consumer-presented payload. It does not
+// verify the signature or check replay; use a CPMVerifier for that.
+func DecodeConsumerQR(payload string) (*ConsumerQR, error) {
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: consumer QR is not base64", ErrInvalidValue)
+	}
+	top, err := parseBERTLV(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(top) != 2 || top[0].tag != cpmTagFormat || top[1].tag != cpmTagAppTmpl {
+		return nil, fmt.Errorf("%w: consumer QR must hold tags 85 and 61", ErrInvalidValue)
+	}
+	if string(top[0].value) != CPMFormat {
+		return nil, fmt.Errorf("%w: payload format %q", ErrInvalidValue, top[0].value)
+	}
+
+	inner, err := parseBERTLV(top[1].value)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string][]byte, len(inner))
+	for _, t := range inner {
+		fields[t.tag] = t.value
+	}
+	for _, tag := range []string{cpmTagAID, cpmTagAlias, cpmTagCounter, cpmTagToken, cpmTagExpiryDate, cpmTagExpiryTime, cpmTagSignature} {
+		if _, ok := fields[tag]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingTag, tag)
+		}
+	}
+	if aid, _ := hex.DecodeString(CPMApplicationID); !bytes.Equal(fields[cpmTagAID], aid) {
+		return nil, fmt.Errorf("%w: unknown application %X", ErrInvalidValue, fields[cpmTagAID])
+	}
+	if len(fields[cpmTagCounter]) != 2 {
+		return nil, fmt.Errorf("%w: counter must be 2 bytes", ErrInvalidValue)
+	}
+	expiry, err := parseBCDTime(fields[cpmTagExpiryDate], fields[cpmTagExpiryTime])
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ConsumerQR{
+		Alias:     string(fields[cpmTagAlias]),
+		Counter:   binary.BigEndian.Uint16(fields[cpmTagCounter]),
+		Token:     fields[cpmTagToken],
+		Expiry:    expiry,
+		Signature: fields[cpmTagSignature],
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// VerifySignature checks the payer's signature against key.
+func (c *ConsumerQR) VerifySignature(key ed25519.PublicKey) error {
+	if len(c.Signature) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, c.signedContent(), c.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// CounterStore remembers the highest counter accepted per alias.
+// Implementations must make Advance atomic, so that two tills scanning the
+// same QR cannot both accept it.
+type CounterStore interface {
+	// Advance records counter for alias if it is higher than any counter
+	// recorded before, and fails with ErrReplay otherwise.
+	Advance(ctx context.Context, alias string, counter uint16) error
+}
+
+// MemoryCounterStore is an in-process CounterStore. Its history is lost on
+// restart and not shared with other tills, so it only stops replays within
+// one process; several tills accepting the same payers need a shared store.
+type MemoryCounterStore struct {
+	mu       sync.Mutex
+	counters map[string]uint16
+}
+
+// NewMemoryCounterStore creates an empty in-memory store.
+func NewMemoryCounterStore() *MemoryCounterStore {
+	return &MemoryCounterStore{counters: make(map[string]uint16)}
+}
+
+// Advance implements the CounterStore interface.
+func (s *MemoryCounterStore) Advance(ctx context.Context, alias string, counter uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.counters[alias]; ok && counter <= last {
+		return fmt.Errorf("%w: %s counter %d, last accepted %d", ErrReplay, alias, counter, last)
+	}
+	s.counters[alias] = counter
+	return nil
+}
+
+// CPMVerifier accepts consumer QRs at a till. It records the highest
+// counter seen per alias in a CounterStore, so a QR can be used once even
+// if the payer shows it again before it expires. The zero value is not
+// usable; call NewCPMVerifier.
+type CPMVerifier struct {
+	counters CounterStore
+}
+
+// NewCPMVerifier returns a verifier backed by a new MemoryCounterStore,
+// which only protects a single till process.
+func NewCPMVerifier() *CPMVerifier {
+	return NewCPMVerifierWithStore(NewMemoryCounterStore())
+}
+
+// NewCPMVerifierWithStore returns a verifier that records counters in
+// store, e.g. one shared by every till in a canteen.
+func NewCPMVerifierWithStore(store CounterStore) *CPMVerifier {
+	return &CPMVerifier{counters: store}
+}
+
+// Verify decodes payload and accepts it if the signature matches key, it
+// has not expired at now, and its counter is higher than any accepted
+// before for the same alias. Tills without connectivity can pass a key
+// cached from an earlier MW-ALS lookup.
+func (v *CPMVerifier) Verify(ctx context.Context, payload string, key ed25519.PublicKey, now time.Time) (*ConsumerQR, error) {
+	c, err := DecodeConsumerQR(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.VerifySignature(key); err != nil {
+		return nil, err
+	}
+	if !now.Before(c.Expiry) {
+		return nil, fmt.Errorf("%w: consumer QR expired at %s", ErrExpired, c.Expiry.Format(time.RFC3339))
+	}
+	if c.Expiry.Sub(now) > MaxConsumerQRTTL {
+		return nil, fmt.Errorf("%w: expiry %s is too far ahead", ErrInvalidValue, c.Expiry.Format(time.RFC3339))
+	}
+	if err := v.counters.Advance(ctx, mwals.Normalizer(c.Alias), c.Counter); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// VerifyWithResolver is Verify using the key the payer's alias publishes in
+// MW-ALS. The resolution must carry a valid signature by registryKey, name
+// the same alias and be ACTIVE.
+func (v *CPMVerifier) VerifyWithResolver(ctx context.Context, payload string, resolver mwals.Resolver, registryKey ed25519.PublicKey, now time.Time) (*ConsumerQR, error) {
+	c, err := DecodeConsumerQR(payload)
+	if err != nil {
+		return nil, err
+	}
+	key, err := resolveKey(ctx, resolver, registryKey, c.Alias)
+	if err != nil {
+		return nil, err
+	}
+	return v.Verify(ctx, payload, key, now)
+}
+
+// validate checks the fields that the generator and decoder share.
+func (c *ConsumerQR) validate() error {
+	if n := len(c.Alias); n < 2 || n > 26 {
+		return fmt.Errorf("%w: alias must be 2-26 characters", ErrInvalidValue)
+	}
+	if err := checkCharset(cpmTagAlias, c.Alias, charsetANS); err != nil {
+		return err
+	}
+	if len(c.Token) != CPMTokenSize {
+		return fmt.Errorf("%w: token must be %d bytes", ErrInvalidValue, CPMTokenSize)
+	}
+	return nil
+}
+
+// appData encodes the application template entries covered by the signature.
+func (c *ConsumerQR) appData() []byte {
+	aid, _ := hex.DecodeString(CPMApplicationID)
+	counter := binary.BigEndian.AppendUint16(nil, c.Counter)
+	date, clock := bcdTime(c.Expiry)
+
+	var b bytes.Buffer
+	b.Write(berTLV(cpmTagAID, aid))
+	b.Write(berTLV(cpmTagLabel, []byte(CPMApplicationLabel)))
+	b.Write(berTLV(cpmTagAlias, []byte(c.Alias)))
+	b.Write(berTLV(cpmTagCounter, counter))
+	b.Write(berTLV(cpmTagToken, c.Token))
+	b.Write(berTLV(cpmTagExpiryDate, date))
+	b.Write(berTLV(cpmTagExpiryTime, clock))
+	return b.Bytes()
+}
+
+// signedContent is the format indicator followed by every application
+// template entry except the signature.
+func (c *ConsumerQR) signedContent() []byte {
+	return append(berTLV(cpmTagFormat, []byte(CPMFormat)), c.appData()...)
+}
+
+// berEntry is one decoded BER-TLV data object. tag is upper-case hex.
+type berEntry struct {
+	tag   string
+	value []byte
+}
+
+// berTLV encodes one data object. tag is hex; lengths up to 255 bytes are supported.
+func berTLV(tag string, value []byte) []byte {
+	out, _ := hex.DecodeString(tag)
+	if len(value) > 127 {
+		out = append(out, 0x81)
+	}
+	out = append(out, byte(len(value)))
+	return append(out, value...)
+}
+
+// parseBERTLV splits a run of BER-TLV data objects.
+func parseBERTLV(data []byte) ([]berEntry, error) {
+	var out []berEntry
+	for i := 0; i < len(data); {
+		start := i
+		if data[i]&0x1F == 0x1F { // Multi-byte tag
+			for i++; i < len(data) && data[i]&0x80 != 0; i++ {
+			}
+		}
+		i++
+		if i >= len(data) {
+			return nil, fmt.Errorf("%w: incomplete tag at offset %d", ErrTruncated, start)
+		}
+		tag := fmt.Sprintf("%X", data[start:i])
+
+		n := int(data[i])
+		i++
+		if n == 0x81 {
+			if i >= len(data) {
+				return nil, fmt.Errorf("%w: tag %s", ErrTruncated, tag)
+			}
+			n = int(data[i])
+			i++
+		} else if n > 0x7F {
+			return nil, fmt.Errorf("%w: tag %s uses unsupported length form %#x", ErrInvalidLength, tag, n)
+		}
+		if i+n > len(data) {
+			return nil, fmt.Errorf("%w: tag %s declares %d bytes, %d remain", ErrTruncated, tag, n, len(data)-i)
+		}
+		out = append(out, berEntry{tag: tag, value: data[i : i+n]})
+		i += n
+	}
+	return out, nil
+}
+
+// bcdTime encodes t in UTC as YYMMDD and hhmmss packed BCD.
+func bcdTime(t time.Time) (date, clock []byte) {
+	t = t.UTC()
+	bcd := func(n int) byte { return byte(n/10<<4 | n%10) }
+	date = []byte{bcd(t.Year() % 100), bcd(int(t.Month())), bcd(t.Day())}
+	clock = []byte{bcd(t.Hour()), bcd(t.Minute()), bcd(t.Second())}
+	return date, clock
+}
+
+// parseBCDTime reverses bcdTime. Two-digit years map to 1969-2068, as in time.Parse.
+func parseBCDTime(date, clock []byte) (time.Time, error) {
+	if len(date) != 3 || len(clock) != 3 {
+		return time.Time{}, fmt.Errorf("%w: expiry must be 3+3 BCD bytes", ErrInvalidValue)
+	}
+	digits := hex.EncodeToString(append(append([]byte{}, date...), clock...))
+	t, err := time.Parse("060102150405", digits)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: expiry %s", ErrInvalidValue, digits)
+	}
+	return t, nil
+}
@@ -0,0 +1,209 @@
+package umqr
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
+)
+
+// ErrUnknownProfile is returned when a payload's country has no registered profile.
+var ErrUnknownProfile = errors.New("umqr: no national QR profile for country")
+
+// Currencies of the neighbouring schemes (ISO 4217 numeric).
+const (
+	CurrencyZMW = "967" // Zambian kwacha
+	CurrencyTZS = "834" // Tanzanian shilling
+)
+
+// Profile describes an EMVCo-based national QR scheme by the GUID,
+// currency and country that mark its domestic merchant accounts.
+type Profile struct {
+	Name     string
+	Country  string // Tag 58, ISO 3166-1 alpha-2
+	Currency string // Tag 53, ISO 4217 numeric
+	GlobalID string // Sub-tag 00 of the national switch's merchant account templates
+	// Provisional marks a profile whose GlobalID is a placeholder, not an
+	// identifier published by the scheme. Translate refuses it unless
+	// TranslateOptions.AllowProvisional is set.
+	Provisional bool
+}
+
+// Profiles for the Mwanza (Zambia) and Songwe (Tanzania) border posts. The
+// Zambian and Tanzanian GUIDs are unofficial placeholders following the
+// Malawi pattern, so both profiles are provisional until each scheme
+// publishes its identifier.
+var (
+	ProfileMalawi   = Profile{Name: "Malawi UMQR", Country: CountryMalawi, Currency: CurrencyMWK, GlobalID: MalawiGlobalID}
+	ProfileZambia   = Profile{Name: "Zambia QR (provisional)", Country: "ZM", Currency: CurrencyZMW, GlobalID: "ZM.GOV.NATSWITCH", Provisional: true}
+	ProfileTanzania = Profile{Name: "Tanzania QR (provisional)", Country: "TZ", Currency: CurrencyTZS, GlobalID: "TZ.GOV.NATSWITCH", Provisional: true}
+)
+
+// profiles indexes the known profiles by country.
+var profiles = map[string]Profile{
+	ProfileMalawi.Country:   ProfileMalawi,
+	ProfileZambia.Country:   ProfileZambia,
+	ProfileTanzania.Country: ProfileTanzania,
+}
+
+// ProfileFor returns the registered profile for an ISO 3166 country code.
+func ProfileFor(country string) (Profile, bool) {
+	p, ok := profiles[country]
+	return p, ok
+}
+
+// TranslateOptions controls how amounts cross the currency boundary.
+type TranslateOptions struct {
+	// FXRate is the exact number of target currency units per source unit,
+	// e.g. big.NewRat(155, 10000). When nil, amounts (Tags 54 and 56)
+	// cannot be mapped and are dropped.
+	FXRate *big.Rat
+
+	// AllowProvisional permits profiles with placeholder GUIDs, for pilots
+	// and testing.
+	AllowProvisional bool
+}
+
+// UnmappedField is a tag the translator dropped, with the reason.
+type UnmappedField struct {
+	Tag    string
+	Reason string
+}
+
+func (u UnmappedField) String() string {
+	return fmt.Sprintf("tag %s: %s", u.Tag, u.Reason)
+}
+
+// Translation is the result of Translate.
+type Translation struct {
+	Payload  string
+	From, To Profile
+	Unmapped []UnmappedField // Empty when every field carried over
+}
+
+// Translate rewrites a payload from its own national profile, detected from
+// Tag 58, into profile to. It rewrites the national switch GUID in Tags
+// 26-51, the currency and the country, and converts amounts when an FX rate
+// is given. Fields with no equivalent in the target scheme, such as
+// national extension templates, signatures or amounts without a rate, are
+// dropped and listed in Unmapped. Other tags are copied unchanged. When the
+// target is Malawi the result must pass Decode.
+func Translate(payload string, to Profile, opts TranslateOptions) (*Translation, error) {
+	payload = strings.TrimSpace(payload)
+	tags, err := ParseTLV(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyCRC(payload); err != nil {
+		return nil, err
+	}
+
+	get := func(tag string) string {
+		for _, t := range tags {
+			if t.Tag == tag {
+				return t.Value
+			}
+		}
+		return ""
+	}
+	from, ok := ProfileFor(get(TagCountryCode))
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProfile, get(TagCountryCode))
+	}
+	for _, p := range []Profile{from, to} {
+		if p.Provisional && !opts.AllowProvisional {
+			return nil, fmt.Errorf("%w: %s has no published GUID; set AllowProvisional to use it", ErrUnknownProfile, p.Name)
+		}
+	}
+	if c := get(TagTransactionCurrency); c != from.Currency {
+		return nil, fmt.Errorf("%w: currency %s does not match %s profile (%s)", ErrInvalidValue, c, from.Name, from.Currency)
+	}
+
+	res := &Translation{From: from, To: to}
+	drop := func(tag, format string, args ...any) {
+		res.Unmapped = append(res.Unmapped, UnmappedField{Tag: tag, Reason: fmt.Sprintf(format, args...)})
+	}
+	sameCurrency := from.Currency == to.Currency
+	hasRate := opts.FXRate != nil && opts.FXRate.Sign() > 0
+
+	var out []TLV
+	for _, t := range tags {
+		switch {
+		case t.Tag == TagCRC:
+			continue
+		case t.Tag == TagCountryCode:
+			t.Value = to.Country
+		case t.Tag == TagTransactionCurrency:
+			t.Value = to.Currency
+		case t.Tag == TagTransactionAmount || t.Tag == TagConvenienceFeeFixed:
+			if !sameCurrency {
+				if !hasRate {
+					drop(t.Tag, "amount in %s needs an FX rate to %s", from.Currency, to.Currency)
+					continue
+				}
+				amount, err := mwjson.ParseMoney(t.Value)
+				if err != nil {
+					return nil, fmt.Errorf("%w: tag %s amount %q", ErrInvalidValue, t.Tag, t.Value)
+				}
+				// Both sides count in hundredths of their currency.
+				converted, err := amount.MulRat(opts.FXRate)
+				if err != nil {
+					return nil, fmt.Errorf("%w: tag %s: %v", ErrInvalidValue, t.Tag, err)
+				}
+				t.Value = converted.String()
+			}
+		case t.Tag == TagMalawiSignature && from.Country == CountryMalawi:
+			drop(t.Tag, "signature does not cover the translated payload")
+			continue
+		case isMerchantAccountTag(t.Tag) || (IsTemplateID(t.Tag) && t.Tag >= "80"):
+			tmpl, err := ParseTemplate(t.Tag, t.Value)
+			if err != nil {
+				return nil, err
+			}
+			if subTag(tmpl, SubTagGlobalID) != from.GlobalID {
+				break // International or third-party scheme; valid on both sides
+			}
+			if !isMerchantAccountTag(t.Tag) {
+				drop(t.Tag, "%s extension template has no %s equivalent", from.Name, to.Name)
+				continue
+			}
+			t = tmpl.Set(SubTagGlobalID, to.GlobalID).TLV()
+		}
+		out = append(out, t)
+	}
+
+	if from.Currency != to.Currency && get(TagPointOfInitiationMethod) == InitiationDynamic && !hasTag(out, TagTransactionAmount) {
+		return nil, fmt.Errorf("%w: dynamic QR needs an FX rate to carry its amount", ErrInvalidValue)
+	}
+
+	res.Payload = assemble(out)
+	if to.Country == CountryMalawi {
+		if _, err := Decode(res.Payload); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// assemble writes tags in ascending order with Tag 00 first, then the CRC.
+func assemble(tags []TLV) string {
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+	var b strings.Builder
+	for _, t := range tags {
+		b.WriteString(t.String())
+	}
+	b.WriteString(TagCRC + "04")
+	return b.String() + fmt.Sprintf("%04X", CalculateCRC16CCITT([]byte(b.String())))
+}
+
+func hasTag(tags []TLV, tag string) bool {
+	for _, t := range tags {
+		if t.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package umqr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
+)
+
+// Decoding errors. Callers can match them with errors.Is.
+var (
+	ErrTruncated        = errors.New("umqr: truncated payload")
+	ErrInvalidLength    = errors.New("umqr: invalid length field")
+	ErrChecksumMismatch = errors.New("umqr: CRC checksum mismatch")
+	ErrMissingTag       = errors.New("umqr: missing mandatory tag")
+)
+
+// MerchantAccount holds the sub-tags of a merchant account template (Tags 26-51).
+type MerchantAccount struct {
+	GlobalID    string // Sub-tag 00, e.g. "MW.GOV.NATSWITCH"
+	AccountType string // Sub-tag 01, e.g. "AIRTEL_MONEY"
+	Alias       string // Sub-tag 02, e.g. "@mubas_cafe"
+}
+
+// Template builds the merchant account template under the given tag ID.
+func (a MerchantAccount) Template(id string) *Template {
+	return NewTemplate(id).
+		Set(SubTagGlobalID, a.GlobalID).
+		Set(SubTagAccountType, a.AccountType).
+		Set(SubTagAliasName, a.Alias)
+}
+
+// MerchantQR is the typed view of a decoded UMQR payload.
+type MerchantQR struct {
+	PayloadFormat     string
+	PointOfInitiation string
+	MerchantAccount   MerchantAccount   // Tag 26, the preferred rail
+	Accounts          []MerchantAccount // Tags 26-51 in priority order
+	MCC               string
+	Currency          string
+	Amount            mwjson.Money // Zero when Tag 54 is absent
+	CountryCode       string
+	MerchantName      string
+	MerchantCity      string
+	Tip               Tip              // Tags 55-57
+	AdditionalData    AdditionalData   // Tag 62
+	Language          MerchantLanguage // Tag 64; zero when absent
+	Expiry            time.Time        // Tag 80, sub-tag 01; zero for static QRs
+	CRC               string
+
+	// Tags holds every top-level TLV in payload order, including ones
+	// this package does not interpret.
+	Tags []TLV
+
+	templates map[string]*Template
+}
+
+// IsDynamic reports whether the QR was generated for a single transaction.
+func (q *MerchantQR) IsDynamic() bool {
+	return q.PointOfInitiation == InitiationDynamic
+}
+
+// Tag returns the raw value of a top-level tag.
+func (q *MerchantQR) Tag(tag string) (string, bool) {
+	for _, t := range q.Tags {
+		if t.Tag == tag {
+			return t.Value, true
+		}
+	}
+	return "", false
+}
+
+// AccountFor returns the highest-priority merchant account on the given rail,
+// so a payer app can pick the account it is able to pay.
+func (q *MerchantQR) AccountFor(provider string) (MerchantAccount, bool) {
+	for _, acc := range q.Accounts {
+		if acc.AccountType == provider {
+			return acc, true
+		}
+	}
+	return MerchantAccount{}, false
+}
+
+// Template returns a decoded template tag such as 26 or 62.
+func (q *MerchantQR) Template(id string) (*Template, bool) {
+	t, ok := q.templates[id]
+	return t, ok
+}
+
+// mandatoryTags must be present in every UMQR payload.
+var mandatoryTags = []string{
+	TagPayloadFormatIndicator,
+	TagPointOfInitiationMethod,
+	TagMalawiMerchantAccount,
+	TagTransactionCurrency,
+	TagCountryCode,
+	TagMerchantName,
+	TagMerchantCity,
+	TagCRC,
+}
+
+// ParseTLV splits a TLV string into its entries without interpreting them.
+// Lengths are counted in characters, matching TLV.Length.
+func ParseTLV(data string) ([]TLV, error) {
+	var out []TLV
+	runes := []rune(data)
+	for i := 0; i < len(runes); {
+		if len(runes)-i < 4 {
+			return nil, fmt.Errorf("%w: incomplete tag header at offset %d", ErrTruncated, i)
+		}
+		tag := string(runes[i : i+2])
+		length := string(runes[i+2 : i+4])
+		n, err := strconv.Atoi(length)
+		if err != nil || n < 0 || !isNumeric(length) {
+			return nil, fmt.Errorf("%w: tag %s has length %q", ErrInvalidLength, tag, length)
+		}
+		start := i + 4
+		if start+n > len(runes) {
+			return nil, fmt.Errorf("%w: tag %s declares %d characters, %d remain", ErrTruncated, tag, n, len(runes)-start)
+		}
+		out = append(out, TLV{Tag: tag, Value: string(runes[start : start+n])})
+		i = start + n
+	}
+	return out, nil
+}
+
+// IsTemplateID reports whether a top-level tag carries nested TLVs.
+// Templates are the merchant account range (26-51), additional data (62),
+// the merchant language template (64) and the unreserved range (80-99).
+func IsTemplateID(tag string) bool {
+	n, err := strconv.Atoi(tag)
+	if err != nil || len(tag) != 2 {
+		return false
+	}
+	return (n >= 26 && n <= 51) || n == 62 || n == 64 || (n >= 80 && n <= 99)
+}
+
+// VerifyCRC checks the trailing Tag 63 checksum of a payload.
+func VerifyCRC(payload string) error {
+	if len(payload) < 8 || payload[len(payload)-8:len(payload)-4] != TagCRC+"04" {
+		return fmt.Errorf("%w: tag %s must terminate the payload", ErrMissingTag, TagCRC)
+	}
+	want := fmt.Sprintf("%04X", CalculateCRC16CCITT([]byte(payload[:len(payload)-4])))
+	got := strings.ToUpper(payload[len(payload)-4:])
+	if got != want {
+		return fmt.Errorf("%w: got %s, want %s", ErrChecksumMismatch, got, want)
+	}
+	return nil
+}
+
+// Decode parses a scanned UMQR payload, verifies its CRC and returns the typed result.
+func Decode(payload string) (*MerchantQR, error) {
+	payload = strings.TrimSpace(payload)
+
+	tags, err := ParseTLV(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyCRC(payload); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		seen[t.Tag] = true
+	}
+	for _, tag := range mandatoryTags {
+		if !seen[tag] {
+			return nil, fmt.Errorf("%w: %s", ErrMissingTag, tag)
+		}
+	}
+
+	q := &MerchantQR{Tags: tags, templates: make(map[string]*Template)}
+	for _, t := range tags {
+		sub := &Template{ID: t.Tag}
+		if IsTemplateID(t.Tag) {
+			if sub, err = ParseTemplate(t.Tag, t.Value); err != nil {
+				return nil, err
+			}
+			q.templates[t.Tag] = sub
+		}
+
+		if isMerchantAccountTag(t.Tag) {
+			acc := MerchantAccount{
+				GlobalID:    subTag(sub, SubTagGlobalID),
+				AccountType: subTag(sub, SubTagAccountType),
+				Alias:       subTag(sub, SubTagAliasName),
+			}
+			q.Accounts = append(q.Accounts, acc)
+			if t.Tag == TagMalawiMerchantAccount {
+				q.MerchantAccount = acc
+			}
+			continue
+		}
+
+		switch t.Tag {
+		case TagPayloadFormatIndicator:
+			q.PayloadFormat = t.Value
+		case TagPointOfInitiationMethod:
+			q.PointOfInitiation = t.Value
+		case TagMerchantCategoryCode:
+			if err := validateValue(t.Tag, t.Value); err != nil {
+				return nil, err
+			}
+			q.MCC = t.Value
+		case TagTransactionCurrency:
+			q.Currency = t.Value
+		case TagTransactionAmount:
+			amount, err := mwjson.ParseMoney(t.Value)
+			if err != nil {
+				return nil, fmt.Errorf("umqr: invalid amount %q", t.Value)
+			}
+			q.Amount = amount
+		case TagCountryCode:
+			q.CountryCode = t.Value
+		case TagMerchantName:
+			q.MerchantName = t.Value
+		case TagMerchantCity:
+			q.MerchantCity = t.Value
+		case TagAdditionalData:
+			q.AdditionalData = parseAdditionalData(sub)
+		case TagMerchantLanguage:
+			if q.Language, err = parseMerchantLanguage(sub); err != nil {
+				return nil, err
+			}
+		case TagMalawiExtensions:
+			if subTag(sub, SubTagGlobalID) != MalawiGlobalID {
+				continue
+			}
+			if v := subTag(sub, SubTagExpiry); v != "" {
+				expiry, err := time.Parse(ExpiryLayout, v)
+				if err != nil {
+					return nil, fmt.Errorf("%w: expiry %q", ErrInvalidValue, v)
+				}
+				q.Expiry = expiry
+			}
+		case TagCRC:
+			q.CRC = t.Value
+		}
+	}
+
+	if q.Tip, err = tipFromTags(q.Tag); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// subTag returns the value of a sub-tag, or "" if it is absent.
+func subTag(t *Template, tag string) string {
+	v, _ := t.Get(tag)
+	return v
+}
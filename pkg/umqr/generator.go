@@ -1,31 +1,103 @@
 package umqr
 
-import "fmt"
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"strconv"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
+)
+
+// Merchant account templates occupy the EMVCo range 26-51.
+const (
+	firstMerchantAccountTag = 26
+	lastMerchantAccountTag  = 51
+	MaxMerchantAccounts     = lastMerchantAccountTag - firstMerchantAccountTag + 1
+)
 
 // GenerateMerchantQR creates a standard UMQR string for a merchant.
-func GenerateMerchantQR(merchantName, city, alias, provider string, amount float64, reference string) string {
+// mcc must be in the registry (see LookupMCC). It returns an error if any
+// field breaks the UMQR length or format rules.
+func GenerateMerchantQR(merchantName, city, alias, provider, mcc string, amount mwjson.Money, reference string) (string, error) {
+	return GenerateMultiAccountQR(merchantName, city, mcc, []MerchantAccount{
+		{AccountType: provider, Alias: alias},
+	}, amount, reference)
+}
+
+// GenerateMultiAccountQR creates a single static sticker that accepts payment
+// on several rails, e.g. Airtel Money, TNM Mpamba, NBM and FDH. Accounts are
+// written to templates 26, 27, ... in the order given, so the first account
+// is the merchant's preferred rail. An empty GlobalID defaults to MalawiGlobalID.
+func GenerateMultiAccountQR(merchantName, city, mcc string, accounts []MerchantAccount, amount mwjson.Money, reference string) (string, error) {
+	enc, err := newMerchantEncoder(merchantName, city, mcc, accounts...)
+	if err != nil {
+		return "", err
+	}
+
+	if amount.IsPositive() {
+		enc.Set(TagTransactionAmount, amount.String())
+	}
+
+	if err := enc.SetAdditionalData(AdditionalData{BillNumber: reference}); err != nil {
+		return "", err
+	}
+
+	return enc.Encode()
+}
+
+// newMerchantEncoder sets the tags shared by every merchant-presented QR.
+func newMerchantEncoder(merchantName, city, mcc string, accounts ...MerchantAccount) (*Encoder, error) {
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("%w: at least one merchant account is required", ErrInvalidValue)
+	}
+	if len(accounts) > MaxMerchantAccounts {
+		return nil, fmt.Errorf("%w: %d merchant accounts, maximum is %d", ErrInvalidValue, len(accounts), MaxMerchantAccounts)
+	}
+
 	enc := NewEncoder()
 	enc.Set(TagPayloadFormatIndicator, "01")
-	enc.Set(TagPointOfInitiationMethod, "11") // Static Sticker
+	enc.Set(TagPointOfInitiationMethod, InitiationStatic)
 
-	// Malawi Specific Interop Data
-	tag26Value := EncodeTag26(provider, alias)
-	enc.Set(TagMalawiMerchantAccount, tag26Value)
+	// Malawi Specific Interop Data, one template per rail in priority order
+	seen := make(map[string]bool, len(accounts))
+	for i, acc := range accounts {
+		if acc.AccountType == "" || acc.Alias == "" {
+			return nil, fmt.Errorf("%w: merchant account %d needs a provider and alias", ErrInvalidValue, i+1)
+		}
+		if seen[acc.AccountType] {
+			return nil, fmt.Errorf("%w: duplicate merchant account for %s", ErrInvalidValue, acc.AccountType)
+		}
+		seen[acc.AccountType] = true
 
-	enc.Set(TagMerchantCategoryCode, "5411") // Default to Grocery Stores
-	enc.Set(TagTransactionCurrency, "454")   // MWK
-
-	if amount > 0 {
-		enc.Set(TagTransactionAmount, fmt.Sprintf("%.2f", amount))
+		if acc.GlobalID == "" {
+			acc.GlobalID = MalawiGlobalID
+		}
+		enc.SetTemplate(acc.Template(strconv.Itoa(firstMerchantAccountTag + i)))
 	}
 
-	enc.Set(TagCountryCode, "MW")
+	enc.Set(TagMerchantCategoryCode, mcc)
+	enc.Set(TagTransactionCurrency, CurrencyMWK)
+	enc.Set(TagCountryCode, CountryMalawi)
 	enc.Set(TagMerchantName, merchantName)
 	enc.Set(TagMerchantCity, city)
+	return enc, nil
+}
 
-	if reference != "" {
-		enc.Set(TagAdditionalData, TLV{Tag: "01", Value: reference}.String())
-	}
+// isMerchantAccountTag reports whether a tag is in the 26-51 range.
+func isMerchantAccountTag(tag string) bool {
+	n, err := strconv.Atoi(tag)
+	return err == nil && len(tag) == 2 && n >= firstMerchantAccountTag && n <= lastMerchantAccountTag
+}
 
-	return enc.Encode()
+// VerificationCode returns a short human-readable fingerprint of a payload,
+// e.g. "K7QX-M2PD". It is printed under the QR on stickers and in the batch
+// manifest as a typo and transcription check: support staff can read it out
+// to confirm a printed or scanned payload is the one in the manifest. It is
+// not a security feature, since whoever prints a replacement sticker prints
+// its code too; use signed stickers (Tag 81) to detect replacement.
+func VerificationCode(payload string) string {
+	sum := sha256.Sum256([]byte(payload))
+	code := base32.StdEncoding.EncodeToString(sum[:5])
+	return code[:4] + "-" + code[4:]
 }
@@ -0,0 +1,179 @@
+package umqr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lint rule IDs reported in Violation.Rule.
+const (
+	RuleSyntax    = "syntax"    // TLV structure cannot be parsed
+	RuleCRC       = "crc"       // Tag 63 missing, misplaced or wrong
+	RuleOrder     = "order"     // Tag 00 first, tags ascending, Tag 63 last
+	RuleDuplicate = "duplicate" // Top-level tag appears more than once
+	RuleMandatory = "mandatory" // Mandatory tag missing
+	RuleValue     = "value"     // Length, charset or allowed values of a tag
+	RuleTemplate  = "template"  // Template sub-tags
+	RuleRegistry  = "registry"  // Value missing from a UMQR registry, e.g. MCCs
+)
+
+// Violation severities. Warnings do not stop a payload from conforming.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Violation is one broken EMVCo or Malawi rule found by Lint.
+type Violation struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Tag      string `json:"tag,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (v Violation) String() string {
+	rule := v.Rule
+	if v.Severity == SeverityWarning {
+		rule += " " + SeverityWarning
+	}
+	if v.Tag != "" {
+		return fmt.Sprintf("[%s] tag %s: %s", rule, v.Tag, v.Message)
+	}
+	return fmt.Sprintf("[%s] %s", rule, v.Message)
+}
+
+// Conforms reports whether violations holds no errors, only warnings.
+func Conforms(violations []Violation) bool {
+	for _, v := range violations {
+		if v.Severity != SeverityWarning {
+			return false
+		}
+	}
+	return true
+}
+
+// Lint checks a payload against every UMQR rule and returns all violations,
+// in payload order where possible. A payload conforms when the result holds
+// no errors (see Conforms); values missing from a registry, such as an MCC
+// outside mcc.go, are only warnings because Decode accepts them.
+// Unlike Decode it does not stop at the first problem, so banks can certify
+// printed stickers in one pass.
+func Lint(payload string) []Violation {
+	payload = strings.TrimSpace(payload)
+	var out []Violation
+	add := func(rule, tag, format string, args ...any) {
+		out = append(out, Violation{Rule: rule, Severity: SeverityError, Tag: tag, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(rule, tag, format string, args ...any) {
+		out = append(out, Violation{Rule: rule, Severity: SeverityWarning, Tag: tag, Message: fmt.Sprintf(format, args...)})
+	}
+
+	tags, err := ParseTLV(payload)
+	if err != nil {
+		add(RuleSyntax, "", "%v", err)
+		return out
+	}
+	if len(tags) == 0 {
+		add(RuleSyntax, "", "payload is empty")
+		return out
+	}
+	if err := VerifyCRC(payload); err != nil {
+		add(RuleCRC, TagCRC, "%v", err)
+	}
+
+	// Ordering
+	if tags[0].Tag != TagPayloadFormatIndicator {
+		add(RuleOrder, tags[0].Tag, "tag %s must be first", TagPayloadFormatIndicator)
+	}
+	for i := 1; i < len(tags); i++ {
+		if tags[i].Tag < tags[i-1].Tag && tags[i].Tag != TagCRC {
+			add(RuleOrder, tags[i].Tag, "follows tag %s; tags must be in ascending order", tags[i-1].Tag)
+		}
+	}
+
+	// Presence
+	count := make(map[string]int, len(tags))
+	for _, t := range tags {
+		count[t.Tag]++
+		if count[t.Tag] == 2 {
+			add(RuleDuplicate, t.Tag, "appears more than once")
+		}
+	}
+	for _, tag := range mandatoryTags {
+		if count[tag] == 0 {
+			add(RuleMandatory, tag, "is missing")
+		}
+	}
+	get := func(tag string) (string, bool) {
+		for _, t := range tags {
+			if t.Tag == tag {
+				return t.Value, true
+			}
+		}
+		return "", false
+	}
+	if v, _ := get(TagPointOfInitiationMethod); v == InitiationDynamic && count[TagTransactionAmount] == 0 {
+		add(RuleMandatory, TagTransactionAmount, "is required when tag %s is %s", TagPointOfInitiationMethod, InitiationDynamic)
+	}
+
+	// Values
+	for _, t := range tags {
+		if err := validateTLV(t); err != nil {
+			add(RuleValue, t.Tag, "%v", err)
+			continue
+		}
+		if IsTemplateID(t.Tag) {
+			for _, msg := range lintTemplate(t) {
+				add(RuleTemplate, t.Tag, "%s", msg)
+			}
+			continue
+		}
+		if t.Tag == TagCRC {
+			continue
+		}
+		if err := validateValue(t.Tag, t.Value); err != nil {
+			add(RuleValue, t.Tag, "%v", err)
+		} else if err := checkRegistry(t.Tag, t.Value); err != nil {
+			warn(RuleRegistry, t.Tag, "%v", err)
+		}
+	}
+	if _, err := tipFromTags(get); err != nil {
+		add(RuleValue, TagTipIndicator, "%v", err)
+	}
+
+	return out
+}
+
+// lintTemplate returns the problems in one template tag.
+func lintTemplate(t TLV) []string {
+	tmpl, err := ParseTemplate(t.Tag, t.Value)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var out []string
+	if err := tmpl.Validate(); err != nil {
+		out = append(out, err.Error())
+	}
+
+	switch {
+	case isMerchantAccountTag(t.Tag):
+		guid := subTag(tmpl, SubTagGlobalID)
+		if guid == "" {
+			out = append(out, "missing globally unique identifier (sub-tag 00)")
+		} else if t.Tag == TagMalawiMerchantAccount && guid != MalawiGlobalID {
+			out = append(out, fmt.Sprintf("tag %s is reserved for %s, got %q", t.Tag, MalawiGlobalID, guid))
+		}
+		if t.Tag == TagMalawiMerchantAccount && subTag(tmpl, SubTagAliasName) == "" {
+			out = append(out, "missing alias (sub-tag 02)")
+		}
+	case t.Tag == TagAdditionalData:
+		if err := parseAdditionalData(tmpl).Validate(); err != nil {
+			out = append(out, err.Error())
+		}
+	case t.Tag == TagMerchantLanguage:
+		if _, err := parseMerchantLanguage(tmpl); err != nil {
+			out = append(out, err.Error())
+		}
+	}
+	return out
+}
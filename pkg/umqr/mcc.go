@@ -0,0 +1,131 @@
+package umqr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MCC is a Merchant Category Code (Tag 52).
+type MCC struct {
+	Code        string
+	Description string
+	Malawi      bool // Malawi national addition, not assigned by ISO 18245
+}
+
+// Malawi national additions use the range 9800-9899, which ISO 18245 leaves unassigned.
+const (
+	MCCCampusCanteen = "9801"
+	MCCMinibus       = "9802"
+	MCCMarketVendor  = "9803"
+	MCCSchoolFees    = "9804"
+)
+
+// mccRegistry lists the codes accepted in UMQR payloads.
+var mccRegistry = map[string]MCC{}
+
+func init() {
+	iso := map[string]string{
+		"0742": "Veterinary Services",
+		"1520": "General Contractors",
+		"4111": "Local and Suburban Commuter Passenger Transportation",
+		"4121": "Taxicabs and Limousines",
+		"4131": "Bus Lines",
+		"4214": "Motor Freight Carriers and Trucking",
+		"4511": "Airlines and Air Carriers",
+		"4722": "Travel Agencies and Tour Operators",
+		"4812": "Telecommunication Equipment and Telephone Sales",
+		"4814": "Telecommunication Services",
+		"4900": "Utilities - Electric, Gas, Water, Sanitary",
+		"5200": "Home Supply Warehouse Stores",
+		"5251": "Hardware Stores",
+		"5311": "Department Stores",
+		"5399": "Miscellaneous General Merchandise",
+		"5411": "Grocery Stores and Supermarkets",
+		"5422": "Freezer and Locker Meat Provisioners",
+		"5441": "Candy, Nut and Confectionery Stores",
+		"5451": "Dairy Products Stores",
+		"5462": "Bakeries",
+		"5499": "Miscellaneous Food Stores",
+		"5541": "Service Stations",
+		"5542": "Automated Fuel Dispensers",
+		"5651": "Family Clothing Stores",
+		"5661": "Shoe Stores",
+		"5732": "Electronics Stores",
+		"5812": "Eating Places and Restaurants",
+		"5813": "Drinking Places (Bars, Taverns, Nightclubs)",
+		"5814": "Fast Food Restaurants",
+		"5912": "Drug Stores and Pharmacies",
+		"5942": "Book Stores",
+		"5943": "Stationery, Office and School Supply Stores",
+		"5999": "Miscellaneous and Specialty Retail Stores",
+		"6010": "Financial Institutions - Manual Cash Disbursements",
+		"6011": "Financial Institutions - Automated Cash Disbursements",
+		"6012": "Financial Institutions - Merchandise and Services",
+		"6300": "Insurance Sales, Underwriting and Premiums",
+		"7011": "Hotels, Motels and Resorts",
+		"7210": "Laundry, Cleaning and Garment Services",
+		"7230": "Beauty and Barber Shops",
+		"7299": "Miscellaneous Personal Services",
+		"7399": "Business Services",
+		"7523": "Parking Lots and Garages",
+		"7538": "Automotive Service Shops",
+		"7832": "Motion Picture Theatres",
+		"7941": "Commercial Sports and Sports Clubs",
+		"7997": "Membership Clubs (Sports, Recreation, Athletic)",
+		"8011": "Doctors and Physicians",
+		"8021": "Dentists and Orthodontists",
+		"8062": "Hospitals",
+		"8099": "Medical Services and Health Practitioners",
+		"8211": "Elementary and Secondary Schools",
+		"8220": "Colleges, Universities and Professional Schools",
+		"8299": "Schools and Educational Services",
+		"8398": "Charitable and Social Service Organizations",
+		"8661": "Religious Organizations",
+		"9311": "Tax Payments",
+		"9399": "Government Services",
+		"9402": "Postal Services - Government Only",
+	}
+	malawi := map[string]string{
+		MCCPersonToPerson: "Person-to-Person Transfer",
+		MCCCampusCanteen:  "Campus Canteen",
+		MCCMinibus:        "Minibus and Kabaza Transport",
+		MCCMarketVendor:   "Market Vendor",
+		MCCSchoolFees:     "School and University Fees",
+	}
+
+	for code, desc := range iso {
+		mccRegistry[code] = MCC{Code: code, Description: desc}
+	}
+	for code, desc := range malawi {
+		mccRegistry[code] = MCC{Code: code, Description: desc, Malawi: true}
+	}
+}
+
+// LookupMCC returns the registry entry for a code.
+func LookupMCC(code string) (MCC, bool) {
+	m, ok := mccRegistry[code]
+	return m, ok
+}
+
+// SearchMCC returns every code whose description contains query,
+// ignoring case, sorted by code.
+func SearchMCC(query string) []MCC {
+	query = strings.ToLower(strings.TrimSpace(query))
+	var out []MCC
+	for _, m := range mccRegistry {
+		if strings.Contains(strings.ToLower(m.Description), query) {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
+
+// ValidateMCC checks that a code is in the registry.
+func ValidateMCC(code string) error {
+	if _, ok := mccRegistry[code]; !ok {
+		return fmt.Errorf("%w: unknown merchant category code %q", ErrInvalidValue, code)
+	}
+	return nil
+}
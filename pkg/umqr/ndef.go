@@ -0,0 +1,294 @@
+package umqr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwals"
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
+)
+
+// ErrInvalidNDEF is returned for malformed NDEF messages or NFC tag memory.
+var ErrInvalidNDEF = errors.New("umqr: invalid NDEF message")
+
+// NDEFType is the NFC Forum external type of the record carrying the raw
+// UMQR TLV. Readers that do not know it fall back to the URI record.
+const NDEFType = "mw.gov:umqr"
+
+// NDEF record header flags and type name formats.
+const (
+	ndefMB         = 0x80 // Message begin
+	ndefME         = 0x40 // Message end
+	ndefCF         = 0x20 // Chunk flag
+	ndefSR         = 0x10 // Short record
+	ndefIL         = 0x08 // ID length present
+	ndefTNFMask    = 0x07
+	ndefTNFWellKnw = 0x01
+	ndefTNFExt     = 0x04
+)
+
+// NTAG memory TLV types (NFC Forum Type 2 Tag).
+const (
+	ntagNull       = 0x00
+	ntagNDEF       = 0x03
+	ntagTerminator = 0xFE
+)
+
+// uriPrefixes are the NFC Forum URI record abbreviations, indexed by code.
+var uriPrefixes = []string{
+	"", "http://www.", "https://www.", "http://", "https://", "tel:", "mailto:",
+	"ftp://anonymous:anonymous@", "ftp://ftp.", "ftps://", "sftp://", "smb://",
+	"nfs://", "ftp://", "dav://", "news:", "telnet://", "imap:", "rtsp://",
+	"urn:", "pop:", "sip:", "sips:", "tftp:", "btspp://", "btl2cap://",
+	"btgoep://", "tcpobex://", "irdaobex://", "file://", "urn:epc:id:",
+	"urn:epc:tag:", "urn:epc:pat:", "urn:epc:raw:", "urn:epc:", "urn:nfc:",
+}
+
+// NFCTag is the payment data written to a tap-to-pay sticker.
+type NFCTag struct {
+	Payload string // UMQR payload, validated with Decode
+	URI     string // Optional URI record, e.g. a "mw:" URI for phones without a UMQR reader
+}
+
+// EncodeNDEF builds an NDEF message with a URI record (when URI is set)
+// followed by an external-type record carrying the raw TLV. The payload
+// must decode, so the NFC and QR channels accept exactly the same data,
+// and a "mw:" URI must pay the same account and amount (see checkURI).
+func EncodeNDEF(t NFCTag) ([]byte, error) {
+	q, err := Decode(t.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkURI(q, t.URI); err != nil {
+		return nil, err
+	}
+
+	type record struct {
+		tnf     byte
+		typ     string
+		payload []byte
+	}
+	var records []record
+	if t.URI != "" {
+		if strings.ContainsAny(t.URI, " \t\r\n") {
+			return nil, fmt.Errorf("%w: URI %q contains whitespace", ErrInvalidNDEF, t.URI)
+		}
+		records = append(records, record{ndefTNFWellKnw, "U", encodeURIRecord(t.URI)})
+	}
+	records = append(records, record{ndefTNFExt, NDEFType, []byte(strings.TrimSpace(t.Payload))})
+
+	var b bytes.Buffer
+	for i, r := range records {
+		header := r.tnf
+		if i == 0 {
+			header |= ndefMB
+		}
+		if i == len(records)-1 {
+			header |= ndefME
+		}
+		short := len(r.payload) < 256
+		if short {
+			header |= ndefSR
+		}
+		b.WriteByte(header)
+		b.WriteByte(byte(len(r.typ)))
+		if short {
+			b.WriteByte(byte(len(r.payload)))
+		} else {
+			n := len(r.payload)
+			b.Write([]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+		}
+		b.WriteString(r.typ)
+		b.Write(r.payload)
+	}
+	return b.Bytes(), nil
+}
+
+// DecodeNDEF reads an NDEF message written by EncodeNDEF. Records of other
+// types are ignored. The UMQR record is required and must pass Decode, and
+// a "mw:" URI record must match it as in EncodeNDEF.
+func DecodeNDEF(msg []byte) (NFCTag, error) {
+	var t NFCTag
+	found := false
+	for i := 0; i < len(msg); {
+		if len(msg)-i < 3 {
+			return NFCTag{}, fmt.Errorf("%w: truncated record header", ErrInvalidNDEF)
+		}
+		header := msg[i]
+		if header&ndefCF != 0 {
+			return NFCTag{}, fmt.Errorf("%w: chunked records are not supported", ErrInvalidNDEF)
+		}
+		typeLen := int(msg[i+1])
+		i += 2
+
+		var payloadLen int
+		if header&ndefSR != 0 {
+			payloadLen = int(msg[i])
+			i++
+		} else {
+			if len(msg)-i < 4 {
+				return NFCTag{}, fmt.Errorf("%w: truncated payload length", ErrInvalidNDEF)
+			}
+			payloadLen = int(msg[i])<<24 | int(msg[i+1])<<16 | int(msg[i+2])<<8 | int(msg[i+3])
+			i += 4
+		}
+		idLen := 0
+		if header&ndefIL != 0 {
+			if i >= len(msg) {
+				return NFCTag{}, fmt.Errorf("%w: truncated ID length", ErrInvalidNDEF)
+			}
+			idLen = int(msg[i])
+			i++
+		}
+		if payloadLen < 0 || len(msg)-i < typeLen+idLen+payloadLen {
+			return NFCTag{}, fmt.Errorf("%w: record overruns message", ErrInvalidNDEF)
+		}
+		typ := string(msg[i : i+typeLen])
+		i += typeLen + idLen
+		payload := msg[i : i+payloadLen]
+		i += payloadLen
+
+		switch tnf := header & ndefTNFMask; {
+		case tnf == ndefTNFWellKnw && typ == "U" && t.URI == "":
+			uri, err := decodeURIRecord(payload)
+			if err != nil {
+				return NFCTag{}, err
+			}
+			t.URI = uri
+		case tnf == ndefTNFExt && strings.EqualFold(typ, NDEFType) && !found:
+			t.Payload = string(payload)
+			found = true
+		}
+
+		if header&ndefME != 0 {
+			break
+		}
+	}
+
+	if !found {
+		return NFCTag{}, fmt.Errorf("%w: no %s record", ErrInvalidNDEF, NDEFType)
+	}
+	q, err := Decode(t.Payload)
+	if err != nil {
+		return NFCTag{}, err
+	}
+	if err := checkURI(q, t.URI); err != nil {
+		return NFCTag{}, err
+	}
+	return t, nil
+}
+
+// checkURI rejects a "mw:" URI that pays a different account or amount
+// than q, as mwuri.FromUMQR would build it, so one tag cannot pay different
+// merchants depending on which record the phone reads. Only the provider,
+// recipient and amount fields are read here; other URI schemes are not
+// interpreted.
+func checkURI(q *MerchantQR, uri string) error {
+	rest, ok := strings.CutPrefix(uri, "mw:")
+	if !ok {
+		return nil
+	}
+	fields := strings.Split(rest, ":")
+	if len(fields) != 5 && len(fields) != 6 {
+		return fmt.Errorf("%w: malformed mw: URI %q", ErrInvalidNDEF, uri)
+	}
+	provider, err := url.PathUnescape(fields[2])
+	if err != nil {
+		return fmt.Errorf("%w: malformed mw: URI %q", ErrInvalidNDEF, uri)
+	}
+	recipient, err := url.PathUnescape(fields[3])
+	if err != nil {
+		return fmt.Errorf("%w: malformed mw: URI %q", ErrInvalidNDEF, uri)
+	}
+	var amount mwjson.Money
+	if fields[4] != "" {
+		if amount, err = mwjson.ParseMoney(fields[4]); err != nil {
+			return fmt.Errorf("%w: malformed mw: URI %q", ErrInvalidNDEF, uri)
+		}
+	}
+
+	account, ok := q.AccountFor(provider)
+	if !ok || mwals.Normalizer(account.Alias) != mwals.Normalizer(recipient) {
+		return fmt.Errorf("%w: URI pays %s on %s, which the UMQR record does not list", ErrInvalidNDEF, recipient, provider)
+	}
+	var want mwjson.Money
+	if q.Amount.IsPositive() {
+		if want, err = q.PayableAmount(mwjson.Money{}); err != nil {
+			return err
+		}
+	}
+	if amount != want {
+		return fmt.Errorf("%w: URI amount %s, UMQR record %s", ErrInvalidNDEF, amount, want)
+	}
+	return nil
+}
+
+// WrapNTAG wraps an NDEF message in the NDEF Message TLV and terminator
+// expected in NTAG user memory.
+func WrapNTAG(ndef []byte) []byte {
+	out := []byte{ntagNDEF}
+	if n := len(ndef); n < 0xFF {
+		out = append(out, byte(n))
+	} else {
+		out = append(out, 0xFF, byte(n>>8), byte(n))
+	}
+	out = append(out, ndef...)
+	return append(out, ntagTerminator)
+}
+
+// UnwrapNTAG returns the first NDEF message found in NTAG user memory,
+// skipping NULL padding and other TLV blocks such as lock controls.
+func UnwrapNTAG(mem []byte) ([]byte, error) {
+	for i := 0; i < len(mem); {
+		typ := mem[i]
+		i++
+		switch typ {
+		case ntagNull:
+			continue
+		case ntagTerminator:
+			return nil, fmt.Errorf("%w: no NDEF message TLV", ErrInvalidNDEF)
+		}
+
+		if i >= len(mem) {
+			break
+		}
+		n := int(mem[i])
+		i++
+		if n == 0xFF {
+			if len(mem)-i < 2 {
+				break
+			}
+			n = int(mem[i])<<8 | int(mem[i+1])
+			i += 2
+		}
+		if len(mem)-i < n {
+			break
+		}
+		if typ == ntagNDEF {
+			return mem[i : i+n], nil
+		}
+		i += n
+	}
+	return nil, fmt.Errorf("%w: truncated tag memory", ErrInvalidNDEF)
+}
+
+// encodeURIRecord applies the longest matching URI prefix abbreviation.
+func encodeURIRecord(uri string) []byte {
+	code := 0
+	for i, p := range uriPrefixes {
+		if p != "" && strings.HasPrefix(uri, p) && len(p) > len(uriPrefixes[code]) {
+			code = i
+		}
+	}
+	return append([]byte{byte(code)}, uri[len(uriPrefixes[code]):]...)
+}
+
+func decodeURIRecord(payload []byte) (string, error) {
+	if len(payload) == 0 || int(payload[0]) >= len(uriPrefixes) {
+		return "", fmt.Errorf("%w: bad URI record", ErrInvalidNDEF)
+	}
+	return uriPrefixes[payload[0]] + string(payload[1:]), nil
+}
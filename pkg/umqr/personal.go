@@ -0,0 +1,87 @@
+package umqr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwals"
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
+)
+
+// MCCPersonToPerson marks a personal "pay me" QR. It is the first code of
+// the Malawi national range, alongside MCCCampusCanteen.
+const MCCPersonToPerson = "9800"
+
+// Payment type indicator in the Malawi extension template (Tag 80).
+const (
+	SubTagPaymentType   = "02"
+	PaymentTypePersonal = "P2P"
+)
+
+// PersonalQRCity fills the mandatory merchant city (Tag 60) of personal QRs,
+// which have no trading location.
+const PersonalQRCity = "Malawi"
+
+// GeneratePersonalQR creates a person-to-person UMQR from an ALS alias
+// record. The record's IdentityMask is shown as the payee name and amount is
+// optional. Only the alias and the provider name are embedded; endpoint
+// destinations never are, so it is safe for private aliases.
+func GeneratePersonalQR(record *mwals.AliasRecord, amount mwjson.Money) (string, error) {
+	if record == nil {
+		return "", fmt.Errorf("%w: alias record cannot be nil", ErrInvalidValue)
+	}
+	if record.Status != mwals.AliasStatusActive {
+		return "", fmt.Errorf("%w: alias %s is not active", ErrInvalidValue, record.Alias)
+	}
+	if record.IdentityMask == "" {
+		return "", fmt.Errorf("%w: alias %s has no identity mask to display", ErrInvalidValue, record.Alias)
+	}
+	if _, err := mwjson.NormalizeMSISDN(record.IdentityMask); err == nil {
+		return "", fmt.Errorf("%w: identity mask of %s is a phone number", ErrInvalidValue, record.Alias)
+	}
+
+	endpoint, ok := selectEndpoint(record.Endpoints, "")
+	if !ok {
+		return "", fmt.Errorf("%w: alias %s has no endpoints", ErrInvalidValue, record.Alias)
+	}
+
+	enc, err := newMerchantEncoder(record.IdentityMask, PersonalQRCity, MCCPersonToPerson, MerchantAccount{
+		AccountType: endpoint.Provider,
+		Alias:       "@" + mwals.Normalizer(record.Alias),
+	})
+	if err != nil {
+		return "", err
+	}
+	enc.SetTemplate(NewTemplate(TagMalawiExtensions).
+		Set(SubTagGlobalID, MalawiGlobalID).
+		Set(SubTagPaymentType, PaymentTypePersonal))
+	if amount.IsPositive() {
+		enc.Set(TagTransactionAmount, amount.String())
+	}
+
+	qr, err := enc.Encode()
+	if err != nil {
+		return "", err
+	}
+
+	// Defence in depth: a destination must never leak into the sticker.
+	for _, ep := range record.Endpoints {
+		if ep.Destination != "" && strings.Contains(qr, ep.Destination) {
+			return "", fmt.Errorf("%w: payload would expose an endpoint of %s", ErrInvalidValue, record.Alias)
+		}
+	}
+	return qr, nil
+}
+
+// TxType reports how a payer should classify a payment to this QR:
+// TxTypeP2P for personal QRs, TxTypeC2B for merchants.
+func (q *MerchantQR) TxType() mwjson.TxType {
+	if q.MCC == MCCPersonToPerson {
+		return mwjson.TxTypeP2P
+	}
+	if t, ok := q.Template(TagMalawiExtensions); ok && subTag(t, SubTagGlobalID) == MalawiGlobalID &&
+		subTag(t, SubTagPaymentType) == PaymentTypePersonal {
+		return mwjson.TxTypeP2P
+	}
+	return mwjson.TxTypeC2B
+}
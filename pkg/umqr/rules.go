@@ -0,0 +1,119 @@
+package umqr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+// ErrInvalidValue is returned when a tag value breaks an EMVCo or Malawi rule.
+var ErrInvalidValue = errors.New("umqr: invalid tag value")
+
+// Character sets used by the EMVCo data object formats.
+type charset int
+
+const (
+	charsetAny     charset = iota // Any UTF-8 (alternate language template)
+	charsetNumeric                // "N": digits only
+	charsetANS                    // "ans": printable ASCII, 0x20-0x7E
+)
+
+// tagRule describes the format of a single top-level tag.
+type tagRule struct {
+	minLen  int
+	maxLen  int
+	charset charset
+	pattern *regexp.Regexp // Optional format check
+	allowed []string       // Optional fixed values
+	// Optional registry lookup. Generators enforce it; Decode does not,
+	// so foreign and newly assigned codes still scan.
+	registry func(string) error
+}
+
+var amountPattern = regexp.MustCompile(`^\d+(\.\d{1,2})?$`)
+
+// tagRules holds the per-tag limits for UMQR. Lengths are counted in characters.
+var tagRules = map[string]tagRule{
+	TagPayloadFormatIndicator:  {minLen: 2, maxLen: 2, charset: charsetNumeric, allowed: []string{"01"}},
+	TagPointOfInitiationMethod: {minLen: 2, maxLen: 2, charset: charsetNumeric, allowed: []string{"11", "12"}},
+	TagMerchantCategoryCode:    {minLen: 4, maxLen: 4, charset: charsetNumeric, registry: ValidateMCC},
+	TagTransactionCurrency:     {minLen: 3, maxLen: 3, charset: charsetNumeric, allowed: []string{CurrencyMWK}},
+	TagTransactionAmount:       {minLen: 1, maxLen: 13, charset: charsetANS, pattern: amountPattern},
+	TagTipIndicator:            {minLen: 2, maxLen: 2, charset: charsetNumeric, allowed: []string{string(TipPromptPayer), string(TipFixedFee), string(TipPercentageFee)}},
+	TagConvenienceFeeFixed:     {minLen: 1, maxLen: 13, charset: charsetANS, pattern: amountPattern},
+	TagConvenienceFeePercent:   {minLen: 1, maxLen: 5, charset: charsetANS, pattern: percentagePattern},
+	TagCountryCode:             {minLen: 2, maxLen: 2, charset: charsetANS, allowed: []string{CountryMalawi}},
+	TagMerchantName:            {minLen: 1, maxLen: 25, charset: charsetANS},
+	TagMerchantCity:            {minLen: 1, maxLen: 15, charset: charsetANS},
+	TagPostalCode:              {minLen: 1, maxLen: 10, charset: charsetANS},
+}
+
+// ISO codes fixed by the Malawi profile.
+const (
+	CurrencyMWK   = "454" // ISO 4217 numeric
+	CountryMalawi = "MW"  // ISO 3166-1 alpha-2
+)
+
+// validateValue applies the content rules of a top-level tag.
+func validateValue(tag, value string) error {
+	if IsTemplateID(tag) {
+		return nil // Sub-tags are checked by Template.Validate
+	}
+	rule, ok := tagRules[tag]
+	if !ok {
+		return checkCharset(tag, value, charsetANS)
+	}
+
+	n := utf8.RuneCountInString(value)
+	if n < rule.minLen || n > rule.maxLen {
+		if rule.minLen == rule.maxLen {
+			return fmt.Errorf("%w: tag %s must be %d characters, got %d", ErrInvalidValue, tag, rule.maxLen, n)
+		}
+		return fmt.Errorf("%w: tag %s must be %d-%d characters, got %d", ErrInvalidValue, tag, rule.minLen, rule.maxLen, n)
+	}
+	if err := checkCharset(tag, value, rule.charset); err != nil {
+		return err
+	}
+	if rule.pattern != nil && !rule.pattern.MatchString(value) {
+		return fmt.Errorf("%w: tag %s has malformed value %q", ErrInvalidValue, tag, value)
+	}
+	if len(rule.allowed) > 0 && !contains(rule.allowed, value) {
+		return fmt.Errorf("%w: tag %s must be one of %v, got %q", ErrInvalidValue, tag, rule.allowed, value)
+	}
+	return nil
+}
+
+// checkRegistry applies the registry lookup of a top-level tag, if it has one.
+func checkRegistry(tag, value string) error {
+	if rule, ok := tagRules[tag]; ok && rule.registry != nil {
+		return rule.registry(value)
+	}
+	return nil
+}
+
+// checkCharset reports the first character outside the allowed set.
+func checkCharset(tag, value string, cs charset) error {
+	for _, r := range value {
+		switch cs {
+		case charsetNumeric:
+			if r < '0' || r > '9' {
+				return fmt.Errorf("%w: tag %s must be numeric, found %q", ErrInvalidValue, tag, r)
+			}
+		case charsetANS:
+			if r < 0x20 || r > 0x7E {
+				return fmt.Errorf("%w: tag %s allows printable ASCII only, found %q", ErrInvalidValue, tag, r)
+			}
+		}
+	}
+	return nil
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package umqr
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwals"
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
+)
+
+// Signature errors.
+var (
+	ErrUnsigned         = errors.New("umqr: payload is not signed")
+	ErrInvalidSignature = errors.New("umqr: signature verification failed")
+)
+
+// Malawi signature template (Tag 81). The GUID is kept short because a
+// base64 Ed25519 signature already takes 90 of the template's 99 characters.
+const (
+	TagMalawiSignature = "81"
+	SignatureGUID      = "MWSIG"
+	SubTagSignature    = "01" // Unpadded base64 Ed25519 signature
+)
+
+// Sign adds an Ed25519 signature template to a UMQR payload and returns the
+// new payload with a recomputed CRC. The signature covers every top-level
+// tag except Tag 81 itself and the CRC, in payload order. Signing an already
+// signed payload replaces the old signature.
+func Sign(payload string, key ed25519.PrivateKey) (string, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return "", fmt.Errorf("%w: signing key must be %d bytes, got %d", ErrInvalidValue, ed25519.PrivateKeySize, len(key))
+	}
+	q, err := Decode(payload)
+	if err != nil {
+		return "", err
+	}
+
+	sig := ed25519.Sign(key, []byte(signedContent(q.Tags)))
+	tmpl := NewTemplate(TagMalawiSignature).
+		Set(SubTagGlobalID, SignatureGUID).
+		Set(SubTagSignature, base64.RawStdEncoding.EncodeToString(sig))
+	if err := tmpl.Validate(); err != nil {
+		return "", err
+	}
+
+	// Rebuild in EMVCo order with Tag 81 after any lower tags, CRC last.
+	var b strings.Builder
+	inserted := false
+	for _, t := range q.Tags {
+		if t.Tag == TagCRC || t.Tag == TagMalawiSignature {
+			continue
+		}
+		if !inserted && t.Tag > TagMalawiSignature {
+			b.WriteString(tmpl.TLV().String())
+			inserted = true
+		}
+		b.WriteString(t.String())
+	}
+	if !inserted {
+		b.WriteString(tmpl.TLV().String())
+	}
+	b.WriteString(TagCRC + "04")
+	return b.String() + fmt.Sprintf("%04X", CalculateCRC16CCITT([]byte(b.String()))), nil
+}
+
+// Verify checks the payload's signature template against the merchant's key.
+func Verify(payload string, key ed25519.PublicKey) error {
+	q, err := Decode(payload)
+	if err != nil {
+		return err
+	}
+	return q.VerifySignature(key)
+}
+
+// VerifyWithResolver fetches the public key published in MW-ALS for the
+// Tag 26 alias and checks the payload's signature against it. The
+// resolution must carry a valid signature by registryKey, name the same
+// alias and be ACTIVE. Scanner apps should only show "verified merchant"
+// when this returns nil.
+func VerifyWithResolver(ctx context.Context, payload string, resolver mwals.Resolver, registryKey ed25519.PublicKey) error {
+	q, err := Decode(payload)
+	if err != nil {
+		return err
+	}
+	if !q.IsSigned() {
+		return ErrUnsigned
+	}
+
+	key, err := resolveKey(ctx, resolver, registryKey, q.MerchantAccount.Alias)
+	if err != nil {
+		return err
+	}
+	return q.VerifySignature(key)
+}
+
+// resolveKey looks up the key alias publishes in MW-ALS with the same
+// checks as mwjson.ALSKeyResolver.
+func resolveKey(ctx context.Context, resolver mwals.Resolver, registryKey ed25519.PublicKey, alias string) (ed25519.PublicKey, error) {
+	key, err := mwjson.NewALSKeyResolver(resolver, registryKey).ResolveKey(ctx, mwjson.ALSKeyID(alias))
+	if err != nil {
+		return nil, fmt.Errorf("%w: key for %s: %w", ErrInvalidSignature, alias, err)
+	}
+	return key, nil
+}
+
+// IsSigned reports whether the payload carries a Malawi signature template.
+func (q *MerchantQR) IsSigned() bool {
+	t, ok := q.Template(TagMalawiSignature)
+	return ok && subTag(t, SubTagGlobalID) == SignatureGUID
+}
+
+// VerifySignature checks the decoded signature template against key.
+func (q *MerchantQR) VerifySignature(key ed25519.PublicKey) error {
+	if !q.IsSigned() {
+		return ErrUnsigned
+	}
+	t, _ := q.Template(TagMalawiSignature)
+	sig, err := base64.RawStdEncoding.DecodeString(subTag(t, SubTagSignature))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	if !ed25519.Verify(key, []byte(signedContent(q.Tags)), sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signedContent concatenates the tags covered by the signature.
+func signedContent(tags []TLV) string {
+	var b strings.Builder
+	for _, t := range tags {
+		if t.Tag != TagCRC && t.Tag != TagMalawiSignature {
+			b.WriteString(t.String())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package umqr
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Template is a top-level tag whose value is itself a list of sub-TLVs,
+// such as a merchant account (26-51) or the additional data field (62).
+// Sub-tags keep the order in which they were first set.
+type Template struct {
+	ID   string
+	tags []TLV
+}
+
+// NewTemplate creates an empty template for the given top-level tag.
+func NewTemplate(id string) *Template {
+	return &Template{ID: id}
+}
+
+// ParseTemplate decodes the value of a template tag into its sub-tags.
+func ParseTemplate(id, value string) (*Template, error) {
+	tags, err := ParseTLV(value)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", id, err)
+	}
+	return &Template{ID: id, tags: tags}, nil
+}
+
+// Set adds a sub-tag, or replaces its value if it is already present.
+// It returns the template so calls can be chained.
+func (t *Template) Set(tag, value string) *Template {
+	for i := range t.tags {
+		if t.tags[i].Tag == tag {
+			t.tags[i].Value = value
+			return t
+		}
+	}
+	t.tags = append(t.tags, TLV{Tag: tag, Value: value})
+	return t
+}
+
+// Get returns the value of a sub-tag.
+func (t *Template) Get(tag string) (string, bool) {
+	for _, s := range t.tags {
+		if s.Tag == tag {
+			return s.Value, true
+		}
+	}
+	return "", false
+}
+
+// SubTags returns a copy of the sub-tags in order.
+func (t *Template) SubTags() []TLV {
+	return append([]TLV(nil), t.tags...)
+}
+
+// String returns the concatenated sub-TLVs, i.e. the template's value.
+func (t *Template) String() string {
+	var b strings.Builder
+	for _, s := range t.tags {
+		b.WriteString(s.String())
+	}
+	return b.String()
+}
+
+// TLV returns the template as a single top-level entry.
+func (t *Template) TLV() TLV {
+	return TLV{Tag: t.ID, Value: t.String()}
+}
+
+// Validate checks the template ID, every sub-tag and the total length.
+func (t *Template) Validate() error {
+	if !IsTemplateID(t.ID) {
+		return fmt.Errorf("umqr: tag %q is not a template", t.ID)
+	}
+	if len(t.tags) == 0 {
+		return fmt.Errorf("umqr: template %s is empty", t.ID)
+	}
+	cs := charsetANS
+	if t.ID == "64" {
+		cs = charsetAny // Alternate language names may use any script
+	}
+	for _, s := range t.tags {
+		if err := validateTLV(s); err != nil {
+			return fmt.Errorf("template %s: %w", t.ID, err)
+		}
+		if err := checkCharset(t.ID+"."+s.Tag, s.Value, cs); err != nil {
+			return err
+		}
+	}
+	return validateTLV(t.TLV())
+}
+
+// validateTLV applies the structural rules shared by tags and sub-tags.
+func validateTLV(t TLV) error {
+	if len(t.Tag) != 2 || !isNumeric(t.Tag) {
+		return fmt.Errorf("umqr: invalid tag ID %q", t.Tag)
+	}
+	if len(t.Value) == 0 {
+		return fmt.Errorf("umqr: tag %s has an empty value", t.Tag)
+	}
+	if n := utf8.RuneCountInString(t.Value); n > 99 {
+		return fmt.Errorf("%w: tag %s value is %d characters, maximum is 99", ErrInvalidValue, t.Tag, n)
+	}
+	return nil
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,166 @@
+package umqr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
+)
+
+// Tip and convenience fee tags.
+const (
+	TagTipIndicator          = "55"
+	TagConvenienceFeeFixed   = "56"
+	TagConvenienceFeePercent = "57"
+)
+
+// TipIndicator is the value of Tag 55.
+type TipIndicator string
+
+const (
+	TipNone          TipIndicator = ""
+	TipPromptPayer   TipIndicator = "01" // Payer app asks for a tip
+	TipFixedFee      TipIndicator = "02" // Fixed convenience fee in Tag 56
+	TipPercentageFee TipIndicator = "03" // Percentage convenience fee in Tag 57
+)
+
+var percentagePattern = regexp.MustCompile(`^\d{1,2}(\.\d{1,2})?$|^100(\.0{1,2})?$`)
+
+// Tip describes the tip or surcharge a merchant asks for.
+type Tip struct {
+	Indicator      TipIndicator
+	FixedFee       mwjson.Money // Tag 56, only with TipFixedFee
+	FeeBasisPoints int64        // Tag 57 in hundredths of a percent, only with TipPercentageFee; 250 means 2.5%
+}
+
+// Validate checks that the fee fields match the indicator.
+func (t Tip) Validate() error {
+	switch t.Indicator {
+	case TipNone, TipPromptPayer:
+		if !t.FixedFee.IsZero() || t.FeeBasisPoints != 0 {
+			return fmt.Errorf("%w: convenience fees require tip indicator %s or %s", ErrInvalidValue, TipFixedFee, TipPercentageFee)
+		}
+	case TipFixedFee:
+		if !t.FixedFee.IsPositive() {
+			return fmt.Errorf("%w: tip indicator %s requires a positive fixed fee", ErrInvalidValue, TipFixedFee)
+		}
+		if t.FeeBasisPoints != 0 {
+			return fmt.Errorf("%w: tip indicator %s does not allow a percentage fee", ErrInvalidValue, TipFixedFee)
+		}
+	case TipPercentageFee:
+		if t.FeeBasisPoints <= 0 || t.FeeBasisPoints > 10000 {
+			return fmt.Errorf("%w: tip indicator %s requires a percentage in (0, 100]", ErrInvalidValue, TipPercentageFee)
+		}
+		if !t.FixedFee.IsZero() {
+			return fmt.Errorf("%w: tip indicator %s does not allow a fixed fee", ErrInvalidValue, TipPercentageFee)
+		}
+	default:
+		return fmt.Errorf("%w: unknown tip indicator %q", ErrInvalidValue, t.Indicator)
+	}
+	return nil
+}
+
+// Apply returns the amount the payer must send. tip is the amount the payer
+// chose and is only allowed when the merchant prompts for one. Percentage
+// fees are computed exactly and rounded half away from zero to the nearest
+// tambala.
+func (t Tip) Apply(amount, tip mwjson.Money) (mwjson.Money, error) {
+	if err := t.Validate(); err != nil {
+		return mwjson.Money{}, err
+	}
+	if tip.Cmp(mwjson.Money{}) < 0 {
+		return mwjson.Money{}, fmt.Errorf("%w: tip cannot be negative", ErrInvalidValue)
+	}
+	if tip.IsPositive() && t.Indicator != TipPromptPayer {
+		return mwjson.Money{}, fmt.Errorf("%w: merchant does not accept tips", ErrInvalidValue)
+	}
+
+	switch t.Indicator {
+	case TipFixedFee:
+		amount = amount.Add(t.FixedFee)
+	case TipPercentageFee:
+		fee, err := amount.Percent(t.FeeBasisPoints)
+		if err != nil {
+			return mwjson.Money{}, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+		}
+		amount = amount.Add(fee)
+	}
+	return amount.Add(tip), nil
+}
+
+// SetTip validates the tip settings and stores Tags 55-57.
+func (e *Encoder) SetTip(t Tip) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	for _, tag := range []string{TagTipIndicator, TagConvenienceFeeFixed, TagConvenienceFeePercent} {
+		delete(e.tags, tag)
+	}
+	if t.Indicator == TipNone {
+		return nil
+	}
+
+	e.Set(TagTipIndicator, string(t.Indicator))
+	switch t.Indicator {
+	case TipFixedFee:
+		e.Set(TagConvenienceFeeFixed, t.FixedFee.String())
+	case TipPercentageFee:
+		e.Set(TagConvenienceFeePercent, formatBasisPoints(t.FeeBasisPoints))
+	}
+	return nil
+}
+
+// PayableAmount returns the QR amount plus any convenience fee and the
+// payer's tip. The result is what goes into mwjson.Payload.Amount.
+func (q *MerchantQR) PayableAmount(tip mwjson.Money) (mwjson.Money, error) {
+	if !q.Amount.IsPositive() {
+		return mwjson.Money{}, fmt.Errorf("%w: %s", ErrMissingTag, TagTransactionAmount)
+	}
+	return q.Tip.Apply(q.Amount, tip)
+}
+
+// tipFromTags reads Tags 55-57 from the encoder or decoder.
+func tipFromTags(get func(string) (string, bool)) (Tip, error) {
+	var t Tip
+	if v, ok := get(TagTipIndicator); ok {
+		t.Indicator = TipIndicator(v)
+	}
+	if v, ok := get(TagConvenienceFeeFixed); ok {
+		fee, err := mwjson.ParseMoney(v)
+		if err != nil {
+			return Tip{}, fmt.Errorf("%w: tag %s has malformed value %q", ErrInvalidValue, TagConvenienceFeeFixed, v)
+		}
+		t.FixedFee = fee
+	}
+	if v, ok := get(TagConvenienceFeePercent); ok {
+		bp, ok := parseBasisPoints(v)
+		if !ok {
+			return Tip{}, fmt.Errorf("%w: tag %s has malformed value %q", ErrInvalidValue, TagConvenienceFeePercent, v)
+		}
+		t.FeeBasisPoints = bp
+	}
+	return t, t.Validate()
+}
+
+// parseBasisPoints reads a Tag 57 percentage such as "2.5" as 250 basis
+// points, digit by digit rather than through a float.
+func parseBasisPoints(v string) (int64, bool) {
+	if !percentagePattern.MatchString(v) {
+		return 0, false
+	}
+	whole, frac, _ := strings.Cut(v, ".")
+	bp, err := strconv.ParseInt(whole+(frac + "00")[:2], 10, 64)
+	return bp, err == nil
+}
+
+// formatBasisPoints writes basis points as the shortest Tag 57 value, e.g.
+// 250 as "2.5" and 300 as "3".
+func formatBasisPoints(bp int64) string {
+	s := strconv.FormatInt(bp/100, 10)
+	if frac := bp % 100; frac != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%02d", frac), "0")
+	}
+	return s
+}
@@ -3,6 +3,8 @@ package umqr
 import (
 	"fmt"
 	"sort"
+	"strings"
+	"unicode/utf8"
 )
 
 // TLV represents a Tag-Length-Value entry.
@@ -11,9 +13,10 @@ type TLV struct {
 	Value string
 }
 
-// Length returns the value's length as a 2-digit string.
+// Length returns the value's length in characters as a 2-digit string.
+// EMVCo counts characters, not bytes, so multi-byte UTF-8 counts once.
 func (t TLV) Length() string {
-	return fmt.Sprintf("%02d", len(t.Value))
+	return fmt.Sprintf("%02d", utf8.RuneCountInString(t.Value))
 }
 
 // String returns the formatted TLV string (TTLLVV...).
@@ -23,31 +26,97 @@ func (t TLV) String() string {
 
 // Encoder handles building the final QR payload string.
 type Encoder struct {
-	tags map[string]string
+	tags      map[string]string
+	templates map[string]*Template
 }
 
 func NewEncoder() *Encoder {
-	return &Encoder{tags: make(map[string]string)}
+	return &Encoder{
+		tags:      make(map[string]string),
+		templates: make(map[string]*Template),
+	}
 }
 
+// Set stores a primitive tag value, replacing any template with the same ID.
 func (e *Encoder) Set(tag string, value string) {
+	delete(e.templates, tag)
 	e.tags[tag] = value
 }
 
-// Encode assembles the tags into a single string, appends the CRC tag (63),
-// calculates the CRC, and returns the full payload.
-func (e *Encoder) Encode() string {
+// SetTemplate stores a nested template, replacing any value with the same ID.
+func (e *Encoder) SetTemplate(t *Template) {
+	delete(e.tags, t.ID)
+	e.templates[t.ID] = t
+}
+
+// Validate checks every tag and template against the TLV structure rules
+// and the per-tag length, character set and format rules.
+func (e *Encoder) Validate() error {
+	for _, t := range e.entries() {
+		if tmpl, ok := e.templates[t.Tag]; ok {
+			if err := tmpl.Validate(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := validateTLV(t); err != nil {
+			return err
+		}
+		if err := validateValue(t.Tag, t.Value); err != nil {
+			return err
+		}
+		if err := checkRegistry(t.Tag, t.Value); err != nil {
+			return err
+		}
+	}
+
+	// Tip and convenience fee tags only make sense in valid combinations.
+	_, err := tipFromTags(func(tag string) (string, bool) {
+		v, ok := e.tags[tag]
+		return v, ok
+	})
+	return err
+}
+
+// entries returns the top-level TLVs in EMVCo order: ascending tag ID,
+// so 00 comes first. Tag 63 is never included; Encode appends it last.
+func (e *Encoder) entries() []TLV {
 	var keys []string
 	for k := range e.tags {
 		keys = append(keys, k)
 	}
+	for k := range e.templates {
+		keys = append(keys, k)
+	}
 	sort.Strings(keys)
 
-	payload := ""
+	out := make([]TLV, 0, len(keys))
 	for _, k := range keys {
-		payload += TLV{Tag: k, Value: e.tags[k]}.String()
+		if k == TagCRC {
+			continue
+		}
+		if t, ok := e.templates[k]; ok {
+			out = append(out, t.TLV())
+			continue
+		}
+		out = append(out, TLV{Tag: k, Value: e.tags[k]})
+	}
+	return out
+}
+
+// Encode validates the tags, assembles them into a single string, appends
+// the CRC tag (63), calculates the CRC, and returns the full payload.
+func (e *Encoder) Encode() (string, error) {
+	if err := e.Validate(); err != nil {
+		return "", err
 	}
 
+	var b strings.Builder
+	for _, t := range e.entries() {
+		b.WriteString(t.String())
+	}
+	payload := b.String()
+
 	// Append Tag 63 (CRC) placeholder "6304"
 	payload += "6304"
 
@@ -55,7 +124,7 @@ func (e *Encoder) Encode() string {
 	crcValue := CalculateCRC16CCITT([]byte(payload))
 	crcStr := fmt.Sprintf("%04X", crcValue)
 
-	return payload + crcStr
+	return payload + crcStr, nil
 }
 
 // Tag definitions based on EMVCo & Malawi Standard
@@ -69,10 +138,14 @@ const (
 	TagCountryCode             = "58" // "MW"
 	TagMerchantName            = "59"
 	TagMerchantCity            = "60"
+	TagPostalCode              = "61"
 	TagAdditionalData          = "62" // Ref strings, Invoice #
 	TagCRC                     = "63"
 )
 
+// MalawiGlobalID identifies the national switch in sub-tag 00 of Malawi templates.
+const MalawiGlobalID = "MW.GOV.NATSWITCH"
+
 // Tag 26 Sub-tags for Malawi
 const (
 	SubTagGlobalID    = "00" // "MW.GOV.NATSWITCH"
@@ -82,8 +155,9 @@ const (
 
 // EncodeTag26 handles the complex Tag 26 mapping.
 func EncodeTag26(accountType, alias string) string {
-	gID := TLV{Tag: SubTagGlobalID, Value: "MW.GOV.NATSWITCH"}.String()
-	aType := TLV{Tag: SubTagAccountType, Value: accountType}.String()
-	aName := TLV{Tag: SubTagAliasName, Value: alias}.String()
-	return gID + aType + aName
+	return MerchantAccount{
+		GlobalID:    MalawiGlobalID,
+		AccountType: accountType,
+		Alias:       alias,
+	}.Template(TagMalawiMerchantAccount).String()
 }
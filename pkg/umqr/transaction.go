@@ -0,0 +1,134 @@
+package umqr
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwals"
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
+)
+
+// DraftTTL is the header TTL, in seconds, for transactions drafted from static QRs.
+const DraftTTL = 300
+
+// DraftTransaction turns a scanned QR into a prefilled, unsigned MW-JSON
+// transaction. It resolves the merchant alias through MW-ALS and pays the
+// endpoint on the payer's own provider when the merchant has one. The type is
+// TxTypeC2B for merchants and TxTypeP2P for personal QRs.
+//
+// Dynamic QRs derive MsgID and IdempotencyKey from the merchant alias and
+// bill reference (see TransactionHeader). Static QRs get fresh IDs. Either
+// way the Tag 62 bill number, or failing that the reference label, becomes
+// Payload.Reference so the merchant can reconcile the payment. When the QR carries no amount
+// Payload.Amount is left zero for the payer to fill in. Aliases that are not
+// ACTIVE cannot be paid.
+func DraftTransaction(ctx context.Context, q *MerchantQR, resolver mwals.Resolver, payer mwjson.Participant) (*mwjson.Transaction, error) {
+	if q.Currency != CurrencyMWK {
+		return nil, fmt.Errorf("%w: currency %s is not MWK", ErrInvalidValue, q.Currency)
+	}
+
+	account, ok := q.AccountFor(string(payer.Provider))
+	if !ok {
+		account = q.MerchantAccount
+	}
+	res, err := resolver.Resolve(ctx, account.Alias)
+	if err != nil {
+		return nil, mwjson.NewMWError(mwjson.ErrAliasNotFound, "Alias Resolution Failed", err.Error())
+	}
+	if res.Status != mwals.AliasStatusActive {
+		return nil, mwjson.NewMWError(mwjson.ErrUnauthorized, "Alias Not Active", fmt.Sprintf("%s is %s", res.Alias, res.Status))
+	}
+	endpoint, ok := selectEndpoint(res.Endpoints, string(payer.Provider))
+	if !ok {
+		return nil, mwjson.NewMWError(mwjson.ErrAliasNotFound, "No Endpoints", res.Alias)
+	}
+
+	header, err := draftHeader(q)
+	if err != nil {
+		return nil, err
+	}
+
+	var amount mwjson.Money
+	if q.Amount.IsPositive() {
+		if amount, err = q.PayableAmount(mwjson.Money{}); err != nil {
+			return nil, err
+		}
+	}
+
+	receiver := mwjson.Participant{
+		ID:       endpoint.Destination,
+		IDType:   mwjson.IDTypeMSISDN,
+		Provider: mwjson.Provider(endpoint.Provider),
+		Alias:    res.Alias,
+	}
+	switch {
+	case strings.HasPrefix(endpoint.Destination, mwals.TokenPrefix):
+		receiver.IDType = mwjson.IDTypeALSToken
+	case endpoint.Type == mwals.EndpointTypeBankAccount:
+		receiver.IDType = mwjson.IDTypeIBAN
+	}
+
+	reference := q.AdditionalData.BillNumber
+	if reference == "" {
+		reference = q.AdditionalData.ReferenceLabel
+	}
+
+	return &mwjson.Transaction{
+		MWVersion: mwjson.MWJSONVersion,
+		Header:    header,
+		Payload: mwjson.Payload{
+			Amount:    amount,
+			Currency:  mwjson.CurrencyMWK,
+			Type:      q.TxType(),
+			Sender:    payer,
+			Receiver:  receiver,
+			Reference: reference,
+		},
+	}, nil
+}
+
+// selectEndpoint prefers the highest-priority endpoint on the payer's
+// provider, falling back to the highest-priority endpoint overall.
+func selectEndpoint(endpoints []mwals.Endpoint, provider string) (mwals.Endpoint, bool) {
+	var best, fallback *mwals.Endpoint
+	for i := range endpoints {
+		ep := &endpoints[i]
+		if fallback == nil || ep.Priority < fallback.Priority {
+			fallback = ep
+		}
+		if ep.Provider == provider && (best == nil || ep.Priority < best.Priority) {
+			best = ep
+		}
+	}
+	if best == nil {
+		best = fallback
+	}
+	if best == nil {
+		return mwals.Endpoint{}, false
+	}
+	return *best, true
+}
+
+// draftHeader binds dynamic QRs to their bill reference and gives static QRs fresh IDs.
+func draftHeader(q *MerchantQR) (mwjson.Header, error) {
+	now := time.Now()
+	if q.IsDynamic() {
+		return q.TransactionHeader(now)
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return mwjson.Header{}, err
+	}
+	id := "TXN-" + hex.EncodeToString(b)
+	return mwjson.Header{
+		MsgID:          id,
+		Timestamp:      now.UTC(),
+		TTL:            DraftTTL,
+		IdempotencyKey: id,
+	}, nil
+}
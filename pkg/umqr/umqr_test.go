@@ -1,9 +1,20 @@
 package umqr_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwals"
+	"github.com/frankmwase/malawi-pay-standard/pkg/mwjson"
 	"github.com/frankmwase/malawi-pay-standard/pkg/umqr"
 )
 
@@ -20,7 +31,10 @@ func TestCRC16(t *testing.T) {
 }
 
 func TestUMQREncoding(t *testing.T) {
-	qr := umqr.GenerateMerchantQR("MUBAS Cafeteria", "Blantyre", "@mubas_cafe", "AIRTEL_MONEY", 1500.00, "LUNCH-001")
+	qr, err := umqr.GenerateMerchantQR("MUBAS Cafeteria", "Blantyre", "@mubas_cafe", "AIRTEL_MONEY", umqr.MCCCampusCanteen, mwjson.Kwacha(1500), "LUNCH-001")
+	if err != nil {
+		t.Fatalf("GenerateMerchantQR failed: %v", err)
+	}
 
 	// Basic checks
 	if !strings.HasPrefix(qr, "000201") {
@@ -42,3 +56,1150 @@ func TestUMQREncoding(t *testing.T) {
 
 	t.Logf("Generated QR: %s", qr)
 }
+
+func TestDecodeRoundTrip(t *testing.T) {
+	qr, err := umqr.GenerateMerchantQR("MUBAS Cafeteria", "Blantyre", "@mubas_cafe", "AIRTEL_MONEY", umqr.MCCCampusCanteen, mwjson.Kwacha(1500), "LUNCH-001")
+	if err != nil {
+		t.Fatalf("GenerateMerchantQR failed: %v", err)
+	}
+
+	got, err := umqr.Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if got.MerchantName != "MUBAS Cafeteria" || got.MerchantCity != "Blantyre" {
+		t.Errorf("Unexpected merchant: %s / %s", got.MerchantName, got.MerchantCity)
+	}
+	if got.MerchantAccount.Alias != "@mubas_cafe" || got.MerchantAccount.AccountType != "AIRTEL_MONEY" {
+		t.Errorf("Unexpected merchant account: %+v", got.MerchantAccount)
+	}
+	if got.MerchantAccount.GlobalID != "MW.GOV.NATSWITCH" {
+		t.Errorf("Unexpected global ID: %s", got.MerchantAccount.GlobalID)
+	}
+	if got.Amount != mwjson.Kwacha(1500) {
+		t.Errorf("Expected amount 1500.00, got %s", got.Amount)
+	}
+	if got.AdditionalData.BillNumber != "LUNCH-001" {
+		t.Errorf("Expected bill number LUNCH-001, got %s", got.AdditionalData.BillNumber)
+	}
+	if got.Currency != "454" || got.CountryCode != "MW" {
+		t.Errorf("Unexpected currency/country: %s / %s", got.Currency, got.CountryCode)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid, err := umqr.GenerateMerchantQR("MUBAS Cafeteria", "Blantyre", "@mubas_cafe", "AIRTEL_MONEY", umqr.MCCCampusCanteen, mwjson.Money{}, "")
+	if err != nil {
+		t.Fatalf("GenerateMerchantQR failed: %v", err)
+	}
+
+	// Drop the merchant city (Tag 60) and re-checksum so only the missing tag is wrong.
+	enc := umqr.NewEncoder()
+	enc.Set(umqr.TagPayloadFormatIndicator, "01")
+	enc.Set(umqr.TagPointOfInitiationMethod, "11")
+	enc.Set(umqr.TagMalawiMerchantAccount, umqr.EncodeTag26("AIRTEL_MONEY", "@mubas_cafe"))
+	enc.Set(umqr.TagTransactionCurrency, "454")
+	enc.Set(umqr.TagCountryCode, "MW")
+	enc.Set(umqr.TagMerchantName, "MUBAS Cafeteria")
+	noCity, err := enc.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		want    error
+	}{
+		{"bad checksum", valid[:len(valid)-4] + "0000", umqr.ErrChecksumMismatch},
+		{"truncated value", valid[:len(valid)-10], umqr.ErrTruncated},
+		{"non-numeric length", "00AB01", umqr.ErrInvalidLength},
+		{"missing tag", noCity, umqr.ErrMissingTag},
+	}
+
+	for _, tt := range tests {
+		_, err := umqr.Decode(tt.payload)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Decode() error = %v; want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestTemplateBuilder(t *testing.T) {
+	tmpl := umqr.NewTemplate(umqr.TagAdditionalData).
+		Set("05", "REF-1").
+		Set("01", "BILL-9").
+		Set("05", "REF-2") // Replaces in place, keeps first position
+
+	if got, want := tmpl.String(), "0505REF-20106BILL-9"; got != want {
+		t.Errorf("Template.String() = %s; want %s", got, want)
+	}
+	if err := tmpl.Validate(); err != nil {
+		t.Errorf("Expected valid template, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		tmpl *umqr.Template
+	}{
+		{"not a template ID", umqr.NewTemplate("59").Set("00", "x")},
+		{"empty template", umqr.NewTemplate("80")},
+		{"bad sub-tag ID", umqr.NewTemplate("80").Set("0A", "x")},
+		{"template over 99", umqr.NewTemplate("80").Set("00", strings.Repeat("A", 60)).Set("01", strings.Repeat("B", 40))},
+	}
+	for _, tt := range tests {
+		if err := tt.tmpl.Validate(); err == nil {
+			t.Errorf("%s: expected validation error, got nil", tt.name)
+		}
+	}
+}
+
+func TestEncoderOrdering(t *testing.T) {
+	enc := umqr.NewEncoder()
+	enc.Set(umqr.TagMerchantCity, "Zomba")
+	enc.Set(umqr.TagCRC, "FFFF") // Must be ignored; CRC is always computed last
+	enc.SetTemplate(umqr.NewTemplate("80").Set("00", "MW.GOV.NATSWITCH"))
+	enc.Set(umqr.TagPayloadFormatIndicator, "01")
+
+	got, err := enc.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	want := "000201" + "6005Zomba" + "80200016MW.GOV.NATSWITCH" + "6304"
+	if !strings.HasPrefix(got, want) || len(got) != len(want)+4 {
+		t.Errorf("Encode() = %s; want prefix %s and a 4-digit CRC", got, want)
+	}
+	if err := umqr.VerifyCRC(got); err != nil {
+		t.Errorf("VerifyCRC failed: %v", err)
+	}
+}
+
+func TestEncoderValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		tag   string
+		value string
+	}{
+		{"name over 25", umqr.TagMerchantName, strings.Repeat("A", 26)},
+		{"name over 99", umqr.TagMerchantName, strings.Repeat("A", 120)},
+		{"non-ASCII name", umqr.TagMerchantName, "Café Zomba"},
+		{"city over 15", umqr.TagMerchantCity, "Blantyre-Limbe City"},
+		{"non-numeric amount", umqr.TagTransactionAmount, "15OO"},
+		{"three decimals", umqr.TagTransactionAmount, "1500.001"},
+		{"wrong currency", umqr.TagTransactionCurrency, "840"},
+		{"alpha currency", umqr.TagTransactionCurrency, "MWK"},
+		{"wrong country", umqr.TagCountryCode, "ZM"},
+		{"bad initiation", umqr.TagPointOfInitiationMethod, "13"},
+		{"short MCC", umqr.TagMerchantCategoryCode, "541"},
+	}
+
+	for _, tt := range tests {
+		enc := umqr.NewEncoder()
+		enc.Set(umqr.TagPayloadFormatIndicator, "01")
+		enc.Set(tt.tag, tt.value)
+		if _, err := enc.Encode(); !errors.Is(err, umqr.ErrInvalidValue) {
+			t.Errorf("%s: Encode() error = %v; want ErrInvalidValue", tt.name, err)
+		}
+	}
+
+	// Oversized alias inside the Tag 26 template
+	_, err := umqr.GenerateMerchantQR("MUBAS Cafeteria", "Blantyre", "@"+strings.Repeat("a", 80), "AIRTEL_MONEY", umqr.MCCCampusCanteen, mwjson.Money{}, "")
+	if err == nil {
+		t.Error("Expected error for oversized merchant account template, got nil")
+	}
+}
+
+func TestTLVLengthCountsCharacters(t *testing.T) {
+	tlv := umqr.TLV{Tag: "01", Value: "Mzuzu Caf\u00e9"}
+	if got := tlv.Length(); got != "10" {
+		t.Errorf("Length() = %s; want 10", got)
+	}
+
+	parsed, err := umqr.ParseTLV(tlv.String() + "0202OK")
+	if err != nil {
+		t.Fatalf("ParseTLV failed: %v", err)
+	}
+	if len(parsed) != 2 || parsed[0].Value != tlv.Value || parsed[1].Value != "OK" {
+		t.Errorf("ParseTLV() = %+v", parsed)
+	}
+}
+
+func TestDynamicQR(t *testing.T) {
+	expiry := time.Now().Add(5 * time.Minute)
+	qr, err := umqr.GenerateDynamicQR(umqr.DynamicQR{
+		MerchantName: "MUBAS Cafeteria",
+		City:         "Blantyre",
+		Alias:        "@mubas_cafe",
+		Provider:     "AIRTEL_MONEY",
+		MCC:          umqr.MCCCampusCanteen,
+		Amount:       mwjson.Kwacha(2500),
+		Reference:    "TILL1-000042",
+		Expiry:       expiry,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDynamicQR failed: %v", err)
+	}
+
+	got, err := umqr.Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !got.IsDynamic() {
+		t.Errorf("Expected point of initiation 12, got %s", got.PointOfInitiation)
+	}
+	if !got.Expiry.Equal(expiry.UTC().Truncate(time.Second)) {
+		t.Errorf("Expiry = %v; want %v", got.Expiry, expiry.UTC().Truncate(time.Second))
+	}
+
+	now := time.Now()
+	header, err := got.TransactionHeader(now)
+	if err != nil {
+		t.Fatalf("TransactionHeader failed: %v", err)
+	}
+	if header.MsgID != "@mubas_cafe/TILL1-000042" || header.IdempotencyKey != "@mubas_cafe/TILL1-000042" {
+		t.Errorf("Header not bound to merchant and bill reference: %+v", header)
+	}
+
+	// Another merchant's bill with the same reference is a different payment
+	otherQR, err := umqr.GenerateDynamicQR(umqr.DynamicQR{
+		MerchantName: "Poly Bookshop",
+		City:         "Blantyre",
+		Alias:        "@poly_books",
+		Provider:     "AIRTEL_MONEY",
+		MCC:          umqr.MCCCampusCanteen,
+		Amount:       mwjson.Kwacha(2500),
+		Reference:    "TILL1-000042",
+		Expiry:       expiry,
+	})
+	if err != nil {
+		t.Fatalf("GenerateDynamicQR failed: %v", err)
+	}
+	other, _ := umqr.Decode(otherQR)
+	otherHeader, err := other.TransactionHeader(now)
+	if err != nil {
+		t.Fatalf("TransactionHeader failed: %v", err)
+	}
+	if otherHeader.IdempotencyKey == header.IdempotencyKey || otherHeader.MsgID == header.MsgID {
+		t.Errorf("Two merchants' bills share a key: %q", header.IdempotencyKey)
+	}
+	if header.TTL <= 0 || header.TTL > 300 {
+		t.Errorf("TTL = %d; want within the QR lifetime", header.TTL)
+	}
+
+	if _, err := got.TransactionHeader(expiry.Add(time.Second)); !errors.Is(err, umqr.ErrExpired) {
+		t.Errorf("Expected ErrExpired after expiry, got %v", err)
+	}
+}
+
+func TestDynamicQRRequiredFields(t *testing.T) {
+	base := umqr.DynamicQR{
+		MerchantName: "MUBAS Cafeteria",
+		City:         "Blantyre",
+		Alias:        "@mubas_cafe",
+		Provider:     "AIRTEL_MONEY",
+		MCC:          umqr.MCCCampusCanteen,
+		Amount:       mwjson.Kwacha(2500),
+		Reference:    "TILL1-000042",
+		Expiry:       time.Now().Add(time.Minute),
+	}
+
+	noAmount, noRef, noMCC, expired := base, base, base, base
+	noAmount.Amount = mwjson.Money{}
+	noRef.Reference = ""
+	noMCC.MCC = ""
+	expired.Expiry = time.Now().Add(-time.Minute)
+
+	for name, d := range map[string]umqr.DynamicQR{"no amount": noAmount, "no reference": noRef, "no MCC": noMCC, "expired": expired} {
+		if _, err := umqr.GenerateDynamicQR(d); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+
+	static, _ := umqr.GenerateMerchantQR("MUBAS Cafeteria", "Blantyre", "@mubas_cafe", "AIRTEL_MONEY", umqr.MCCCampusCanteen, mwjson.Money{}, "")
+	q, _ := umqr.Decode(static)
+	if _, err := q.TransactionHeader(time.Now()); err == nil {
+		t.Error("Expected error for static QR header, got nil")
+	}
+}
+
+func TestMultiAccountQR(t *testing.T) {
+	accounts := []umqr.MerchantAccount{
+		{AccountType: "AIRTEL_MONEY", Alias: "@mubas_cafe"},
+		{AccountType: "TNM_MPAMBA", Alias: "@mubas_cafe"},
+		{AccountType: "NBM", Alias: "@mubas_cafe_nbm"},
+		{AccountType: "FDH", Alias: "@mubas_cafe_fdh"},
+	}
+
+	qr, err := umqr.GenerateMultiAccountQR("MUBAS Cafeteria", "Blantyre", umqr.MCCCampusCanteen, accounts, mwjson.Money{}, "")
+	if err != nil {
+		t.Fatalf("GenerateMultiAccountQR failed: %v", err)
+	}
+
+	got, err := umqr.Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(got.Accounts) != len(accounts) {
+		t.Fatalf("Expected %d accounts, got %d", len(accounts), len(got.Accounts))
+	}
+	for i, acc := range got.Accounts {
+		if acc.AccountType != accounts[i].AccountType || acc.Alias != accounts[i].Alias {
+			t.Errorf("Account %d = %+v; want %+v", i, acc, accounts[i])
+		}
+		if acc.GlobalID != umqr.MalawiGlobalID {
+			t.Errorf("Account %d GlobalID = %s; want %s", i, acc.GlobalID, umqr.MalawiGlobalID)
+		}
+	}
+	if got.MerchantAccount.AccountType != "AIRTEL_MONEY" {
+		t.Errorf("Expected Tag 26 to hold the preferred rail, got %s", got.MerchantAccount.AccountType)
+	}
+
+	fdh, ok := got.AccountFor("FDH")
+	if !ok || fdh.Alias != "@mubas_cafe_fdh" {
+		t.Errorf("AccountFor(FDH) = %+v, %v", fdh, ok)
+	}
+	if _, ok := got.AccountFor("STANDARD_BANK"); ok {
+		t.Error("Expected no STANDARD_BANK account")
+	}
+
+	dup := append(accounts, umqr.MerchantAccount{AccountType: "NBM", Alias: "@other"})
+	if _, err := umqr.GenerateMultiAccountQR("MUBAS Cafeteria", "Blantyre", umqr.MCCCampusCanteen, dup, mwjson.Money{}, ""); err == nil {
+		t.Error("Expected error for duplicate provider, got nil")
+	}
+	if _, err := umqr.GenerateMultiAccountQR("MUBAS Cafeteria", "Blantyre", umqr.MCCCampusCanteen, nil, mwjson.Money{}, ""); err == nil {
+		t.Error("Expected error for no accounts, got nil")
+	}
+}
+
+func TestAdditionalData(t *testing.T) {
+	want := umqr.AdditionalData{
+		BillNumber:     "INV-42",
+		MobileNumber:   umqr.PromptPayer,
+		StoreLabel:     "Main",
+		LoyaltyNumber:  umqr.PromptPayer,
+		ReferenceLabel: "SEM1",
+		CustomerLabel:  "BED-12-21",
+		TerminalLabel:  "T03",
+		Purpose:        "Fees",
+		RequestMobile:  true,
+		RequestEmail:   true,
+	}
+
+	enc := umqr.NewEncoder()
+	enc.Set(umqr.TagPayloadFormatIndicator, "01")
+	enc.Set(umqr.TagPointOfInitiationMethod, umqr.InitiationStatic)
+	enc.SetTemplate(umqr.MerchantAccount{GlobalID: umqr.MalawiGlobalID, AccountType: "NBM", Alias: "@mubas_fees"}.Template(umqr.TagMalawiMerchantAccount))
+	enc.Set(umqr.TagTransactionCurrency, umqr.CurrencyMWK)
+	enc.Set(umqr.TagCountryCode, umqr.CountryMalawi)
+	enc.Set(umqr.TagMerchantName, "MUBAS Bursar")
+	enc.Set(umqr.TagMerchantCity, "Blantyre")
+	if err := enc.SetAdditionalData(want); err != nil {
+		t.Fatalf("SetAdditionalData failed: %v", err)
+	}
+
+	qr, err := enc.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.Contains(qr, "0902ME") {
+		t.Errorf("Expected consumer data request 0902ME in %s", qr)
+	}
+
+	got, err := umqr.Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if got.AdditionalData != want {
+		t.Errorf("AdditionalData = %+v; want %+v", got.AdditionalData, want)
+	}
+
+	long := umqr.AdditionalData{StoreLabel: strings.Repeat("S", 26)}
+	if err := enc.SetAdditionalData(long); !errors.Is(err, umqr.ErrInvalidValue) {
+		t.Errorf("Expected ErrInvalidValue for 26-character store label, got %v", err)
+	}
+}
+
+func TestTipAndConvenienceFee(t *testing.T) {
+	tests := []struct {
+		name   string
+		tip    umqr.Tip
+		payer  mwjson.Money
+		want   mwjson.Money
+		hasErr bool
+	}{
+		{"no tip", umqr.Tip{}, mwjson.Money{}, mwjson.Kwacha(2000), false},
+		{"prompt with tip", umqr.Tip{Indicator: umqr.TipPromptPayer}, mwjson.Kwacha(150), mwjson.Kwacha(2150), false},
+		{"fixed fee", umqr.Tip{Indicator: umqr.TipFixedFee, FixedFee: mwjson.Kwacha(50)}, mwjson.Money{}, mwjson.Kwacha(2050), false},
+		{"percentage fee", umqr.Tip{Indicator: umqr.TipPercentageFee, FeeBasisPoints: 250}, mwjson.Money{}, mwjson.Kwacha(2050), false},
+		{"tip without prompt", umqr.Tip{Indicator: umqr.TipFixedFee, FixedFee: mwjson.Kwacha(50)}, mwjson.Kwacha(100), mwjson.Money{}, true},
+	}
+
+	for _, tt := range tests {
+		enc := newTestEncoder()
+		enc.Set(umqr.TagTransactionAmount, "2000.00")
+		if err := enc.SetTip(tt.tip); err != nil {
+			t.Fatalf("%s: SetTip failed: %v", tt.name, err)
+		}
+		qr, err := enc.Encode()
+		if err != nil {
+			t.Fatalf("%s: Encode failed: %v", tt.name, err)
+		}
+		q, err := umqr.Decode(qr)
+		if err != nil {
+			t.Fatalf("%s: Decode failed: %v", tt.name, err)
+		}
+		if q.Tip != tt.tip {
+			t.Errorf("%s: decoded Tip = %+v; want %+v", tt.name, q.Tip, tt.tip)
+		}
+
+		got, err := q.PayableAmount(tt.payer)
+		if (err != nil) != tt.hasErr {
+			t.Errorf("%s: PayableAmount() error = %v; want error %v", tt.name, err, tt.hasErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: PayableAmount() = %s; want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPercentageFeeRounding(t *testing.T) {
+	// Each fee is exactly half a tambala, which a float computation lands
+	// just below and rounds down.
+	for _, tt := range []struct {
+		tag  string
+		want mwjson.Money
+	}{
+		{"2.3", mwjson.Tambala(1535)},
+		{"4.1", mwjson.Tambala(1562)},
+		{"5.1", mwjson.Tambala(1577)},
+		{"8.7", mwjson.Tambala(1631)},
+	} {
+		enc := newTestEncoder()
+		enc.Set(umqr.TagTransactionAmount, "15.00")
+		enc.Set(umqr.TagTipIndicator, string(umqr.TipPercentageFee))
+		enc.Set(umqr.TagConvenienceFeePercent, tt.tag)
+		qr, err := enc.Encode()
+		if err != nil {
+			t.Fatalf("%s%%: Encode failed: %v", tt.tag, err)
+		}
+		q, err := umqr.Decode(qr)
+		if err != nil {
+			t.Fatalf("%s%%: Decode failed: %v", tt.tag, err)
+		}
+		if got, err := q.PayableAmount(mwjson.Money{}); err != nil || got != tt.want {
+			t.Errorf("15.00 + %s%% = %s, %v; want %s", tt.tag, got, err, tt.want)
+		}
+	}
+
+	enc := newTestEncoder()
+	if err := enc.SetTip(umqr.Tip{Indicator: umqr.TipPercentageFee, FeeBasisPoints: 1205}); err != nil {
+		t.Fatalf("SetTip failed: %v", err)
+	}
+	qr, _ := enc.Encode()
+	if q, _ := umqr.Decode(qr); q == nil || !strings.Contains(qr, "570512.05") || q.Tip.FeeBasisPoints != 1205 {
+		t.Errorf("Tag 57 for 1205 basis points not encoded as 12.05: %s", qr)
+	}
+}
+
+func TestTipCombinations(t *testing.T) {
+	invalid := []umqr.Tip{
+		{Indicator: umqr.TipFixedFee},
+		{Indicator: umqr.TipPercentageFee, FeeBasisPoints: 12000},
+		{Indicator: umqr.TipPromptPayer, FixedFee: mwjson.Kwacha(50)},
+		{Indicator: umqr.TipFixedFee, FixedFee: mwjson.Kwacha(50), FeeBasisPoints: 200},
+		{FixedFee: mwjson.Kwacha(50)},
+		{Indicator: "04"},
+	}
+	for _, tip := range invalid {
+		if err := newTestEncoder().SetTip(tip); !errors.Is(err, umqr.ErrInvalidValue) {
+			t.Errorf("SetTip(%+v) error = %v; want ErrInvalidValue", tip, err)
+		}
+	}
+
+	// Raw tags that break the combination rules are rejected at encode time.
+	enc := newTestEncoder()
+	enc.Set(umqr.TagTipIndicator, string(umqr.TipPromptPayer))
+	enc.Set(umqr.TagConvenienceFeeFixed, "50.00")
+	if _, err := enc.Encode(); err == nil {
+		t.Error("Expected error for Tag 56 with tip indicator 01, got nil")
+	}
+}
+
+// newTestEncoder returns an encoder with every mandatory tag set.
+func newTestEncoder() *umqr.Encoder {
+	enc := umqr.NewEncoder()
+	enc.Set(umqr.TagPayloadFormatIndicator, "01")
+	enc.Set(umqr.TagPointOfInitiationMethod, umqr.InitiationStatic)
+	enc.SetTemplate(umqr.MerchantAccount{GlobalID: umqr.MalawiGlobalID, AccountType: "AIRTEL_MONEY", Alias: "@campus_taxi"}.Template(umqr.TagMalawiMerchantAccount))
+	enc.Set(umqr.TagMerchantCategoryCode, "4121")
+	enc.Set(umqr.TagTransactionCurrency, umqr.CurrencyMWK)
+	enc.Set(umqr.TagCountryCode, umqr.CountryMalawi)
+	enc.Set(umqr.TagMerchantName, "Campus Taxi")
+	enc.Set(umqr.TagMerchantCity, "Zomba")
+	return enc
+}
+
+func TestMerchantLanguage(t *testing.T) {
+	tests := []umqr.MerchantLanguage{
+		{Language: umqr.LanguageChichewa, Name: "Malo Odyera a MUBAS", City: "Blantyre"},
+		{Language: umqr.LanguageChichewa, Name: "Nyumba ya Chakudya", City: "Mzuzu"},
+		{Language: umqr.LanguageChichewa, Name: "Kanyenya wa Ŵanthu"}, // Non-ASCII, no city
+	}
+
+	for _, want := range tests {
+		enc := newTestEncoder()
+		if err := enc.SetMerchantLanguage(want); err != nil {
+			t.Fatalf("SetMerchantLanguage(%+v) failed: %v", want, err)
+		}
+		qr, err := enc.Encode()
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		got, err := umqr.Decode(qr)
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if got.Language != want {
+			t.Errorf("Language = %+v; want %+v", got.Language, want)
+		}
+	}
+
+	invalid := []umqr.MerchantLanguage{
+		{Language: "Chichewa", Name: "Malo Odyera"},
+		{Language: "nya", Name: "Malo Odyera"}, // ISO 639-2 is not allowed
+		{Language: "tum", Name: "Nyumba ya Chakurya"},
+		{Language: umqr.LanguageChichewa},
+		{Language: umqr.LanguageChichewa, Name: strings.Repeat("Ŵ", 26)},
+		{Language: umqr.LanguageChichewa, Name: "Nyumba", City: "Mzuzu wa Kumpoto"},
+	}
+	for _, l := range invalid {
+		if err := newTestEncoder().SetMerchantLanguage(l); !errors.Is(err, umqr.ErrInvalidValue) {
+			t.Errorf("SetMerchantLanguage(%+v) error = %v; want ErrInvalidValue", l, err)
+		}
+	}
+}
+
+func TestDraftTransaction(t *testing.T) {
+	_, alsKey, _ := ed25519.GenerateKey(rand.Reader)
+	als, _ := mwals.NewService(alsKey, "")
+	als.Seed(&mwals.AliasRecord{
+		Alias:        "mubas_cafe",
+		Status:       mwals.AliasStatusActive,
+		IdentityMask: "M**** C********",
+		Endpoints: []mwals.Endpoint{
+			{Priority: 1, Provider: "AIRTEL_MONEY", Type: mwals.EndpointTypeWallet, Destination: "265991112223"},
+			{Priority: 2, Provider: "TNM_MPAMBA", Type: mwals.EndpointTypeWallet, Destination: "265881112223"},
+		},
+	})
+
+	payer := mwjson.Participant{
+		ID:       "265881234567",
+		IDType:   mwjson.IDTypeMSISDN,
+		Provider: mwjson.ProviderTNMPamba,
+		Alias:    "@student_john",
+	}
+
+	qr, err := umqr.GenerateDynamicQR(umqr.DynamicQR{
+		MerchantName: "MUBAS Cafeteria",
+		City:         "Blantyre",
+		Alias:        "@mubas_cafe",
+		Provider:     "AIRTEL_MONEY",
+		MCC:          umqr.MCCCampusCanteen,
+		Amount:       mwjson.Kwacha(2500),
+		Reference:    "LUNCH-45",
+		Expiry:       time.Now().Add(5 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("GenerateDynamicQR failed: %v", err)
+	}
+	scanned, err := umqr.Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	tx, err := umqr.DraftTransaction(context.Background(), scanned, als, payer)
+	if err != nil {
+		t.Fatalf("DraftTransaction failed: %v", err)
+	}
+
+	if tx.Payload.Receiver.Provider != mwjson.ProviderTNMPamba || tx.Payload.Receiver.ID != "265881112223" {
+		t.Errorf("Expected the merchant's TNM endpoint, got %+v", tx.Payload.Receiver)
+	}
+	if tx.Payload.Receiver.Alias != "@mubas_cafe" {
+		t.Errorf("Receiver alias = %s; want @mubas_cafe", tx.Payload.Receiver.Alias)
+	}
+	if tx.Payload.Amount != mwjson.Kwacha(2500) || tx.Payload.Type != mwjson.TxTypeC2B {
+		t.Errorf("Unexpected payload: amount %s, type %s", tx.Payload.Amount, tx.Payload.Type)
+	}
+	if tx.Header.MsgID != "@mubas_cafe/LUNCH-45" || tx.Header.IdempotencyKey != "@mubas_cafe/LUNCH-45" {
+		t.Errorf("Header not bound to bill reference: %+v", tx.Header)
+	}
+	if tx.Payload.Reference != "LUNCH-45" {
+		t.Errorf("Payload.Reference = %q; want LUNCH-45", tx.Payload.Reference)
+	}
+	if tx.TrustLayer.Signature != "" {
+		t.Error("Draft must be unsigned")
+	}
+	if err := tx.Validate(); err != nil {
+		t.Errorf("Draft should validate, got %v", err)
+	}
+
+	// Unknown alias
+	unknown, _ := umqr.GenerateMerchantQR("Unknown Shop", "Zomba", "@nobody", "AIRTEL_MONEY", umqr.MCCCampusCanteen, mwjson.Money{}, "")
+	q, _ := umqr.Decode(unknown)
+	if _, err := umqr.DraftTransaction(context.Background(), q, als, payer); err == nil {
+		t.Error("Expected error for unknown alias, got nil")
+	}
+
+	// Static QR keeps its bill number for reconciliation
+	static, _ := umqr.GenerateMerchantQR("MUBAS Cafeteria", "Blantyre", "@mubas_cafe", "AIRTEL_MONEY", umqr.MCCCampusCanteen, mwjson.Money{}, "TABLE-7")
+	q, _ = umqr.Decode(static)
+	tx, err = umqr.DraftTransaction(context.Background(), q, als, payer)
+	if err != nil {
+		t.Fatalf("DraftTransaction failed: %v", err)
+	}
+	if tx.Payload.Reference != "TABLE-7" || tx.Header.MsgID == "TABLE-7" {
+		t.Errorf("Static QR: reference %q, MsgID %q; want reference TABLE-7 and a fresh MsgID", tx.Payload.Reference, tx.Header.MsgID)
+	}
+
+	// Pending alias
+	als.Seed(&mwals.AliasRecord{
+		Alias:     "new_shop",
+		Status:    mwals.AliasStatusPending,
+		Endpoints: []mwals.Endpoint{{Priority: 1, Provider: "AIRTEL_MONEY", Destination: "265991112224"}},
+	})
+	pending, _ := umqr.GenerateMerchantQR("New Shop", "Zomba", "@new_shop", "AIRTEL_MONEY", umqr.MCCCampusCanteen, mwjson.Money{}, "")
+	q, _ = umqr.Decode(pending)
+	if _, err := umqr.DraftTransaction(context.Background(), q, als, payer); err == nil {
+		t.Error("Expected error for pending alias, got nil")
+	}
+
+	// Private alias resolves to a blinded token, not an MSISDN
+	als.Seed(&mwals.AliasRecord{
+		Alias:     "quiet_shop",
+		Status:    mwals.AliasStatusActive,
+		IsPrivate: true,
+		Endpoints: []mwals.Endpoint{{Priority: 1, Provider: "TNM_MPAMBA", Destination: "265881112225"}},
+	})
+	private, _ := umqr.GenerateMerchantQR("Quiet Shop", "Zomba", "@quiet_shop", "TNM_MPAMBA", umqr.MCCCampusCanteen, mwjson.Money{}, "")
+	q, _ = umqr.Decode(private)
+	tx, err = umqr.DraftTransaction(context.Background(), q, als, payer)
+	if err != nil {
+		t.Fatalf("DraftTransaction failed: %v", err)
+	}
+	if tx.Payload.Receiver.IDType != mwjson.IDTypeALSToken {
+		t.Errorf("Receiver IDType = %s; want %s", tx.Payload.Receiver.IDType, mwjson.IDTypeALSToken)
+	}
+}
+
+func TestPersonalQR(t *testing.T) {
+	record := &mwals.AliasRecord{
+		Alias:        "private_user",
+		Status:       mwals.AliasStatusActive,
+		IdentityMask: "P****** U***",
+		IsPrivate:    true,
+		Endpoints: []mwals.Endpoint{
+			{Priority: 2, Provider: "AIRTEL_MONEY", Destination: "265999000111"},
+			{Priority: 1, Provider: "TNM_MPAMBA", Destination: "265888000111"},
+		},
+	}
+
+	qr, err := umqr.GeneratePersonalQR(record, mwjson.Kwacha(500))
+	if err != nil {
+		t.Fatalf("GeneratePersonalQR failed: %v", err)
+	}
+	for _, msisdn := range []string{"265999000111", "265888000111", "999000111", "888000111"} {
+		if strings.Contains(qr, msisdn) {
+			t.Errorf("Personal QR leaks MSISDN %s: %s", msisdn, qr)
+		}
+	}
+
+	got, err := umqr.Decode(qr)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if got.TxType() != mwjson.TxTypeP2P {
+		t.Errorf("TxType() = %s; want P2P", got.TxType())
+	}
+	if got.MerchantName != "P****** U***" || got.MerchantAccount.Alias != "@private_user" {
+		t.Errorf("Unexpected payee: %s / %s", got.MerchantName, got.MerchantAccount.Alias)
+	}
+	if got.MerchantAccount.AccountType != "TNM_MPAMBA" {
+		t.Errorf("Expected highest-priority provider TNM_MPAMBA, got %s", got.MerchantAccount.AccountType)
+	}
+	if got.Amount != mwjson.Kwacha(500) {
+		t.Errorf("Amount = %s; want 500.00", got.Amount)
+	}
+
+	merchant, _ := umqr.GenerateMerchantQR("MUBAS Cafeteria", "Blantyre", "@mubas_cafe", "AIRTEL_MONEY", umqr.MCCCampusCanteen, mwjson.Money{}, "")
+	if q, _ := umqr.Decode(merchant); q.TxType() != mwjson.TxTypeC2B {
+		t.Errorf("Merchant TxType() = %s; want C2B", q.TxType())
+	}
+
+	leaky := *record
+	leaky.IdentityMask = "0999000111"
+	if _, err := umqr.GeneratePersonalQR(&leaky, mwjson.Money{}); err == nil {
+		t.Error("Expected error when the identity mask is a phone number, got nil")
+	}
+}
+
+// tamperingResolver replaces the published key in another resolver's
+// responses, as a spoofed registry or a man in the middle would.
+type tamperingResolver struct {
+	next      mwals.Resolver
+	publicKey string
+}
+
+func (r tamperingResolver) Resolve(ctx context.Context, alias string) (*mwals.ResolutionResponse, error) {
+	res, err := r.next.Resolve(ctx, alias)
+	if err != nil {
+		return nil, err
+	}
+	res.PublicKey = r.publicKey
+	return res, nil
+}
+
+func TestSignedQR(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+
+	qr, err := umqr.GenerateMerchantQR("MUBAS Cafeteria", "Blantyre", "@mubas_cafe", "AIRTEL_MONEY", umqr.MCCCampusCanteen, mwjson.Money{}, "")
+	if err != nil {
+		t.Fatalf("GenerateMerchantQR failed: %v", err)
+	}
+	if err := umqr.Verify(qr, pub); !errors.Is(err, umqr.ErrUnsigned) {
+		t.Errorf("Expected ErrUnsigned for plain sticker, got %v", err)
+	}
+
+	signed, err := umqr.Sign(qr, priv)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if err := umqr.Verify(signed, pub); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+
+	// A forged sticker that reroutes to another alias but keeps the signature.
+	forged := strings.Replace(signed[:len(signed)-4], "@mubas_cafe", "@mubas_cafx", 1)
+	forged += fmt.Sprintf("%04X", umqr.CalculateCRC16CCITT([]byte(forged)))
+	if err := umqr.Verify(forged, pub); !errors.Is(err, umqr.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for forged sticker, got %v", err)
+	}
+
+	// Key published through MW-ALS
+	registryPub, alsKey, _ := ed25519.GenerateKey(rand.Reader)
+	als, _ := mwals.NewService(alsKey, "")
+	als.Seed(&mwals.AliasRecord{
+		Alias:     "mubas_cafe",
+		Status:    mwals.AliasStatusActive,
+		PublicKey: hex.EncodeToString(pub),
+	})
+	ctx := context.Background()
+	if err := umqr.VerifyWithResolver(ctx, signed, als, registryPub); err != nil {
+		t.Errorf("VerifyWithResolver failed: %v", err)
+	}
+
+	// A resolver in the middle swaps in the forger's key
+	forgerPub, forgerKey, _ := ed25519.GenerateKey(rand.Reader)
+	forgedSigned, _ := umqr.Sign(qr, forgerKey)
+	tampered := tamperingResolver{next: als, publicKey: hex.EncodeToString(forgerPub)}
+	if err := umqr.VerifyWithResolver(ctx, forgedSigned, tampered, registryPub); !errors.Is(err, umqr.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for tampered resolution, got %v", err)
+	}
+
+	// Suspended merchants are not verified
+	als.Seed(&mwals.AliasRecord{
+		Alias:     "mubas_cafe",
+		Status:    mwals.AliasStatusSuspended,
+		PublicKey: hex.EncodeToString(pub),
+	})
+	if err := umqr.VerifyWithResolver(ctx, signed, als, registryPub); !errors.Is(err, umqr.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for suspended alias, got %v", err)
+	}
+
+	if _, err := umqr.Sign(qr, nil); !errors.Is(err, umqr.ErrInvalidValue) {
+		t.Errorf("Sign with nil key: got %v, want ErrInvalidValue", err)
+	}
+
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	if err := umqr.Verify(signed, otherPub); !errors.Is(err, umqr.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for wrong key, got %v", err)
+	}
+}
+
+func TestMCCRegistry(t *testing.T) {
+	m, ok := umqr.LookupMCC(umqr.MCCMinibus)
+	if !ok || !m.Malawi || m.Description != "Minibus and Kabaza Transport" {
+		t.Errorf("LookupMCC(%s) = %+v, %v", umqr.MCCMinibus, m, ok)
+	}
+	if m, ok := umqr.LookupMCC("5411"); !ok || m.Malawi {
+		t.Errorf("LookupMCC(5411) = %+v, %v, want ISO entry", m, ok)
+	}
+	if _, ok := umqr.LookupMCC("0000"); ok {
+		t.Error("LookupMCC(0000) found an entry")
+	}
+
+	found := umqr.SearchMCC("school")
+	codes := make([]string, len(found))
+	for i, m := range found {
+		codes[i] = m.Code
+	}
+	if got := strings.Join(codes, ","); got != "5943,8211,8220,8299,9804" {
+		t.Errorf("SearchMCC(school) = %s", got)
+	}
+
+	// Unknown codes are rejected at encode time...
+	enc := newTestEncoder()
+	enc.Set(umqr.TagMerchantCategoryCode, "0000")
+	if _, err := enc.Encode(); !errors.Is(err, umqr.ErrInvalidValue) {
+		t.Errorf("Encode with MCC 0000: got %v, want ErrInvalidValue", err)
+	}
+
+	// ...but any well-formed code decodes, with only a lint warning.
+	qr, err := newTestEncoder().Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	withMCC := func(mcc string) string {
+		body := strings.Replace(qr[:len(qr)-4], "52044121", "5204"+mcc, 1)
+		return body + fmt.Sprintf("%04X", umqr.CalculateCRC16CCITT([]byte(body)))
+	}
+	q, err := umqr.Decode(withMCC("0000"))
+	if err != nil || q.MCC != "0000" {
+		t.Errorf("Decode with MCC 0000: got %v", err)
+	}
+	lint := umqr.Lint(withMCC("0000"))
+	if len(lint) != 1 || lint[0].Rule != umqr.RuleRegistry || lint[0].Severity != umqr.SeverityWarning || !umqr.Conforms(lint) {
+		t.Errorf("Lint with MCC 0000 = %v, want one registry warning", lint)
+	}
+	if _, err := umqr.Decode(withMCC("12A4")); !errors.Is(err, umqr.ErrInvalidValue) {
+		t.Errorf("Decode with MCC 12A4: got %v, want ErrInvalidValue", err)
+	}
+}
+
+func TestConsumerQR(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	now := time.Now()
+	ctx := context.Background()
+
+	qr, err := umqr.GenerateConsumerQR("@student_john", 7, time.Minute, priv)
+	if err != nil {
+		t.Fatalf("GenerateConsumerQR failed: %v", err)
+	}
+	c, err := umqr.DecodeConsumerQR(qr)
+	if err != nil {
+		t.Fatalf("DecodeConsumerQR failed: %v", err)
+	}
+	if c.Alias != "@student_john" || c.Counter != 7 || len(c.Token) != umqr.CPMTokenSize {
+		t.Errorf("Unexpected consumer QR: %+v", c)
+	}
+	if d := c.Expiry.Sub(now); d <= 0 || d > time.Minute {
+		t.Errorf("Expiry %s is not about a minute ahead", c.Expiry)
+	}
+
+	v := umqr.NewCPMVerifier()
+	if _, err := v.Verify(ctx, qr, pub, now); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if _, err := v.Verify(ctx, qr, pub, now); !errors.Is(err, umqr.ErrReplay) {
+		t.Errorf("Second Verify: got %v, want ErrReplay", err)
+	}
+	older, _ := umqr.GenerateConsumerQR("@student_john", 6, time.Minute, priv)
+	if _, err := v.Verify(ctx, older, pub, now); !errors.Is(err, umqr.ErrReplay) {
+		t.Errorf("Verify with lower counter: got %v, want ErrReplay", err)
+	}
+	next, _ := umqr.GenerateConsumerQR("@student_john", 8, time.Minute, priv)
+	if _, err := v.Verify(ctx, next, pub, now.Add(2*time.Minute)); !errors.Is(err, umqr.ErrExpired) {
+		t.Errorf("Verify after expiry: got %v, want ErrExpired", err)
+	}
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := v.Verify(ctx, next, otherPub, now); !errors.Is(err, umqr.ErrInvalidSignature) {
+		t.Errorf("Verify with wrong key: got %v, want ErrInvalidSignature", err)
+	}
+
+	// Key published through MW-ALS
+	registryPub, alsKey, _ := ed25519.GenerateKey(rand.Reader)
+	als, _ := mwals.NewService(alsKey, "")
+	als.Seed(&mwals.AliasRecord{
+		Alias:     "student_john",
+		Status:    mwals.AliasStatusActive,
+		PublicKey: hex.EncodeToString(pub),
+	})
+	if _, err := v.VerifyWithResolver(ctx, next, als, registryPub, now); err != nil {
+		t.Errorf("VerifyWithResolver failed: %v", err)
+	}
+
+	// A spoofed registry cannot vouch for a forger's key
+	forgerPub, forgerKey, _ := ed25519.GenerateKey(rand.Reader)
+	forged, _ := umqr.GenerateConsumerQR("@student_john", 20, time.Minute, forgerKey)
+	tampered := tamperingResolver{next: als, publicKey: hex.EncodeToString(forgerPub)}
+	if _, err := v.VerifyWithResolver(ctx, forged, tampered, registryPub, now); !errors.Is(err, umqr.ErrInvalidSignature) {
+		t.Errorf("VerifyWithResolver with tampered resolution: got %v, want ErrInvalidSignature", err)
+	}
+
+	// Suspended payers cannot pay
+	als.Seed(&mwals.AliasRecord{
+		Alias:     "student_john",
+		Status:    mwals.AliasStatusSuspended,
+		PublicKey: hex.EncodeToString(pub),
+	})
+	later, _ := umqr.GenerateConsumerQR("@student_john", 21, time.Minute, priv)
+	if _, err := v.VerifyWithResolver(ctx, later, als, registryPub, now); !errors.Is(err, umqr.ErrInvalidSignature) {
+		t.Errorf("VerifyWithResolver for suspended alias: got %v, want ErrInvalidSignature", err)
+	}
+
+	// Tills sharing a store reject a QR another till accepted
+	shared := umqr.NewMemoryCounterStore()
+	till1, till2 := umqr.NewCPMVerifierWithStore(shared), umqr.NewCPMVerifierWithStore(shared)
+	fresh, _ := umqr.GenerateConsumerQR("@student_john", 30, time.Minute, priv)
+	if _, err := till1.Verify(ctx, fresh, pub, now); err != nil {
+		t.Fatalf("Verify at first till failed: %v", err)
+	}
+	if _, err := till2.Verify(ctx, fresh, pub, now); !errors.Is(err, umqr.ErrReplay) {
+		t.Errorf("Verify at second till: got %v, want ErrReplay", err)
+	}
+
+	if _, err := umqr.GenerateConsumerQR("@student_john", 9, time.Hour, priv); !errors.Is(err, umqr.ErrInvalidValue) {
+		t.Errorf("GenerateConsumerQR with 1h TTL: got %v, want ErrInvalidValue", err)
+	}
+	if _, err := umqr.DecodeConsumerQR(qr[:20]); err == nil {
+		t.Error("Expected error for truncated consumer QR, got nil")
+	}
+}
+
+func TestLint(t *testing.T) {
+	valid, err := newTestEncoder().Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if v := umqr.Lint(valid); v != nil {
+		t.Errorf("Lint(valid) = %v, want none", v)
+	}
+
+	// Every problem is reported, not just the first.
+	body := "000201" + "010211" +
+		umqr.TLV{Tag: "26", Value: "0009OTHER.GUI0112AIRTEL_MONEY"}.String() +
+		"5303840" + "5802MW" + "5802MW" + "5911Campus Taxi" + "5303454" +
+		umqr.TLV{Tag: "60", Value: "A City Name Far Too Long For Tag 60"}.String()
+	forged := body + "6304FFFF"
+
+	rules := make(map[string]bool)
+	for _, v := range umqr.Lint(forged) {
+		rules[v.Rule+" "+v.Tag] = true
+	}
+	for _, want := range []string{
+		"crc 63",
+		"order 53",     // Second Tag 53 after 59
+		"duplicate 58", // Tag 58 twice
+		"duplicate 53",
+		"template 26", // Wrong GUID and no alias
+		"value 53",    // 840 is not MWK
+		"value 60",    // Longer than 15 characters
+	} {
+		if !rules[want] {
+			t.Errorf("Lint did not report %q; got %v", want, rules)
+		}
+	}
+
+	dynamic := newTestEncoder()
+	dynamic.Set(umqr.TagPointOfInitiationMethod, umqr.InitiationDynamic)
+	qr, _ := dynamic.Encode()
+	if v := umqr.Lint(qr); len(v) != 1 || v[0].Rule != umqr.RuleMandatory || v[0].Tag != umqr.TagTransactionAmount {
+		t.Errorf("Lint(dynamic without amount) = %v", v)
+	}
+
+	if v := umqr.Lint("0002"); len(v) != 1 || v[0].Rule != umqr.RuleSyntax {
+		t.Errorf("Lint(truncated) = %v", v)
+	}
+}
+
+func TestVerificationCode(t *testing.T) {
+	qr, _ := newTestEncoder().Encode()
+	code := umqr.VerificationCode(qr)
+	if len(code) != 9 || code[4] != '-' {
+		t.Errorf("VerificationCode = %q, want XXXX-XXXX", code)
+	}
+	if umqr.VerificationCode(qr) != code {
+		t.Error("VerificationCode is not deterministic")
+	}
+	if umqr.VerificationCode(strings.Replace(qr, "Zomba", "Zombo", 1)) == code {
+		t.Error("VerificationCode did not change with the payload")
+	}
+}
+
+func TestNDEF(t *testing.T) {
+	qr, err := umqr.GenerateMerchantQR("MUBAS Cafeteria", "Blantyre", "@mubas_cafe", "AIRTEL_MONEY", umqr.MCCCampusCanteen, mwjson.Money{}, "")
+	if err != nil {
+		t.Fatalf("GenerateMerchantQR failed: %v", err)
+	}
+
+	for _, tag := range []umqr.NFCTag{
+		{Payload: qr, URI: "mw:1.0:TXN:AIRTEL_MONEY:@mubas_cafe:"},
+		{Payload: qr, URI: "https://pay.mubas.ac.mw/@mubas_cafe"},
+		{Payload: qr},
+	} {
+		msg, err := umqr.EncodeNDEF(tag)
+		if err != nil {
+			t.Fatalf("EncodeNDEF(%+v) failed: %v", tag, err)
+		}
+
+		// NULL padding and a lock control TLV before the NDEF message, as on real NTAGs.
+		mem := append([]byte{0x00, 0x01, 0x03, 0xA0, 0x10, 0x44}, umqr.WrapNTAG(msg)...)
+		unwrapped, err := umqr.UnwrapNTAG(mem)
+		if err != nil {
+			t.Fatalf("UnwrapNTAG failed: %v", err)
+		}
+		got, err := umqr.DecodeNDEF(unwrapped)
+		if err != nil {
+			t.Fatalf("DecodeNDEF failed: %v", err)
+		}
+		if got != tag {
+			t.Errorf("DecodeNDEF = %+v, want %+v", got, tag)
+		}
+	}
+
+	// https:// is abbreviated to prefix code 0x04.
+	msg, _ := umqr.EncodeNDEF(umqr.NFCTag{Payload: qr, URI: "https://pay.mubas.ac.mw"})
+	if !strings.Contains(string(msg), "\x04pay.mubas.ac.mw") {
+		t.Errorf("URI record not abbreviated: %q", msg)
+	}
+
+	// Messages over 254 bytes use the 3-byte NTAG length form.
+	long := umqr.WrapNTAG(make([]byte, 300))
+	if long[1] != 0xFF || int(long[2])<<8|int(long[3]) != 300 {
+		t.Errorf("WrapNTAG length header = % X", long[:4])
+	}
+
+	// The same TLV and CRC validation as the QR channel.
+	bad := qr[:len(qr)-4] + "0000"
+	if _, err := umqr.EncodeNDEF(umqr.NFCTag{Payload: bad}); !errors.Is(err, umqr.ErrChecksumMismatch) {
+		t.Errorf("EncodeNDEF with bad CRC: got %v, want ErrChecksumMismatch", err)
+	}
+	forged := bytes.Replace(msg, []byte(qr[len(qr)-4:]), []byte("0000"), 1)
+	if _, err := umqr.DecodeNDEF(forged); !errors.Is(err, umqr.ErrChecksumMismatch) {
+		t.Errorf("DecodeNDEF with bad CRC: got %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := umqr.DecodeNDEF(msg[:10]); !errors.Is(err, umqr.ErrInvalidNDEF) {
+		t.Errorf("DecodeNDEF truncated: got %v, want ErrInvalidNDEF", err)
+	}
+
+	// The mw: URI must pay the same merchant and amount as the UMQR record
+	priced, _ := umqr.GenerateMerchantQR("MUBAS Cafeteria", "Blantyre", "@mubas_cafe", "AIRTEL_MONEY", umqr.MCCCampusCanteen, mwjson.Kwacha(1500), "")
+	for name, tag := range map[string]umqr.NFCTag{
+		"other merchant": {Payload: qr, URI: "mw:1.0:TXN:AIRTEL_MONEY:@mubas_cafx:"},
+		"other provider": {Payload: qr, URI: "mw:1.0:TXN:TNM_MPAMBA:@mubas_cafe:"},
+		"added amount":   {Payload: qr, URI: "mw:1.0:TXN:AIRTEL_MONEY:@mubas_cafe:1500"},
+		"other amount":   {Payload: priced, URI: "mw:1.0:TXN:AIRTEL_MONEY:@mubas_cafe:15000"},
+	} {
+		if _, err := umqr.EncodeNDEF(tag); !errors.Is(err, umqr.ErrInvalidNDEF) {
+			t.Errorf("EncodeNDEF with %s: got %v, want ErrInvalidNDEF", name, err)
+		}
+	}
+	if _, err := umqr.EncodeNDEF(umqr.NFCTag{Payload: priced, URI: "mw:1.0:TXN:AIRTEL_MONEY:%40mubas_cafe:1500.00"}); err != nil {
+		t.Errorf("EncodeNDEF with matching URI failed: %v", err)
+	}
+	paired, _ := umqr.EncodeNDEF(umqr.NFCTag{Payload: qr, URI: "mw:1.0:TXN:AIRTEL_MONEY:@mubas_cafe:"})
+	rerouted := bytes.Replace(paired, []byte("@mubas_cafe:"), []byte("@mubas_cafx:"), 1)
+	if _, err := umqr.DecodeNDEF(rerouted); !errors.Is(err, umqr.ErrInvalidNDEF) {
+		t.Errorf("DecodeNDEF with mismatched URI: got %v, want ErrInvalidNDEF", err)
+	}
+}
+
+func TestCrossBorderTranslation(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	static, err := umqr.GenerateMultiAccountQR("Mwanza Traders", "Mwanza", umqr.MCCMarketVendor, []umqr.MerchantAccount{
+		{AccountType: "AIRTEL_MONEY", Alias: "@mwanza_traders"},
+		{GlobalID: "com.visa", AccountType: "CARD", Alias: "4000123412341234"},
+	}, mwjson.Kwacha(5000), "")
+	if err != nil {
+		t.Fatalf("GenerateMultiAccountQR failed: %v", err)
+	}
+	signed, err := umqr.Sign(static, priv)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	// The neighbouring GUIDs are placeholders, so they must be opted into.
+	if _, err := umqr.Translate(signed, umqr.ProfileZambia, umqr.TranslateOptions{}); !errors.Is(err, umqr.ErrUnknownProfile) {
+		t.Errorf("Translate to provisional profile: got %v, want ErrUnknownProfile", err)
+	}
+	pilot := umqr.TranslateOptions{AllowProvisional: true}
+
+	// Without an FX rate the amount is dropped and reported, never guessed.
+	res, err := umqr.Translate(signed, umqr.ProfileZambia, pilot)
+	if err != nil {
+		t.Fatalf("Translate failed: %v", err)
+	}
+	dropped := make(map[string]bool)
+	for _, u := range res.Unmapped {
+		dropped[u.Tag] = true
+	}
+	if len(res.Unmapped) != 2 || !dropped["54"] || !dropped["81"] {
+		t.Errorf("Unmapped = %v, want tags 54 and 81", res.Unmapped)
+	}
+	zm, _ := umqr.ParseTLV(res.Payload)
+	want := map[string]string{"53": umqr.CurrencyZMW, "58": "ZM"}
+	for _, tlv := range zm {
+		if v, ok := want[tlv.Tag]; ok && tlv.Value != v {
+			t.Errorf("Tag %s = %q, want %q", tlv.Tag, tlv.Value, v)
+		}
+		if tlv.Tag == "26" && !strings.Contains(tlv.Value, umqr.ProfileZambia.GlobalID) {
+			t.Errorf("Tag 26 GUID not rewritten: %q", tlv.Value)
+		}
+		if tlv.Tag == "27" && !strings.Contains(tlv.Value, "com.visa") {
+			t.Errorf("Third-party Tag 27 should be untouched: %q", tlv.Value)
+		}
+	}
+	if err := umqr.VerifyCRC(res.Payload); err != nil {
+		t.Errorf("Translated CRC: %v", err)
+	}
+
+	// With a rate the amount is converted, and the trip back to Malawi decodes.
+	res, err = umqr.Translate(static, umqr.ProfileZambia, umqr.TranslateOptions{FXRate: big.NewRat(155, 10000), AllowProvisional: true})
+	if err != nil {
+		t.Fatalf("Translate with FX failed: %v", err)
+	}
+	if len(res.Unmapped) != 0 || !strings.Contains(res.Payload, "540577.50") {
+		t.Errorf("Translate with FX = %s, unmapped %v", res.Payload, res.Unmapped)
+	}
+	back, err := umqr.Translate(res.Payload, umqr.ProfileMalawi, umqr.TranslateOptions{FXRate: big.NewRat(10000, 155), AllowProvisional: true})
+	if err != nil {
+		t.Fatalf("Translate back failed: %v", err)
+	}
+	q, err := umqr.Decode(back.Payload)
+	if err != nil {
+		t.Fatalf("Decode of translated payload failed: %v", err)
+	}
+	if q.MerchantAccount.GlobalID != umqr.MalawiGlobalID || q.Amount != mwjson.Kwacha(5000) || q.CountryCode != umqr.CountryMalawi {
+		t.Errorf("Round trip = %+v", q)
+	}
+
+	// Dynamic QRs cannot lose their amount.
+	dynamic, _ := umqr.GenerateDynamicQR(umqr.DynamicQR{
+		MerchantName: "Songwe Grocers", City: "Karonga", Alias: "@songwe", Provider: "TNM_MPAMBA",
+		MCC: "5411", Amount: mwjson.Kwacha(1200), Reference: "BILL-9", Expiry: time.Now().Add(time.Minute),
+	})
+	if _, err := umqr.Translate(dynamic, umqr.ProfileTanzania, pilot); !errors.Is(err, umqr.ErrInvalidValue) {
+		t.Errorf("Translate dynamic without FX: got %v, want ErrInvalidValue", err)
+	}
+	res, err = umqr.Translate(dynamic, umqr.ProfileTanzania, umqr.TranslateOptions{FXRate: big.NewRat(145, 100), AllowProvisional: true})
+	if err != nil {
+		t.Fatalf("Translate dynamic failed: %v", err)
+	}
+	if !strings.Contains(res.Payload, "54071740.00") {
+		t.Errorf("MWK 1200 at 1.45 should be exactly TZS 1740.00: %s", res.Payload)
+	}
+	if len(res.Unmapped) != 1 || res.Unmapped[0].Tag != "80" {
+		t.Errorf("Unmapped = %v, want Malawi extensions (80)", res.Unmapped)
+	}
+
+	// Unknown source country
+	enc := newTestEncoder()
+	qr, _ := enc.Encode()
+	body := strings.Replace(qr[:len(qr)-4], "5802MW", "5802KE", 1)
+	kenya := body + fmt.Sprintf("%04X", umqr.CalculateCRC16CCITT([]byte(body)))
+	if _, err := umqr.Translate(kenya, umqr.ProfileMalawi, umqr.TranslateOptions{}); !errors.Is(err, umqr.ErrUnknownProfile) {
+		t.Errorf("Translate from KE: got %v, want ErrUnknownProfile", err)
+	}
+}